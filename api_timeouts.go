@@ -0,0 +1,57 @@
+package healer
+
+import "encoding/json"
+
+// APITimeouts maps a Kafka API name (see apiKeyByName) to the request
+// timeout, in milliseconds, Broker.Request should use for it. It unmarshals
+// from either the current "timeout.ms.for.eachapi" object form or the
+// legacy fixed-length array form indexed by numeric API key, so existing
+// JSON configs keep working unchanged.
+type APITimeouts map[string]int
+
+func (t *APITimeouts) UnmarshalJSON(data []byte) error {
+	var asMap map[string]int
+	if err := json.Unmarshal(data, &asMap); err == nil {
+		*t = asMap
+		return nil
+	}
+
+	var asArray []int
+	if err := json.Unmarshal(data, &asArray); err != nil {
+		return err
+	}
+
+	m := make(APITimeouts, len(asArray))
+	for apiKey, timeoutMS := range asArray {
+		if name := APIName(int16(apiKey)); name != "" {
+			m[name] = timeoutMS
+		}
+	}
+	*t = m
+	return nil
+}
+
+// timeoutMSFor returns the configured timeout for apiName, falling back to
+// defaultMS when apiName has no entry (or t is nil/empty) rather than
+// silently using a zero timeout.
+func (t APITimeouts) timeoutMSFor(apiName string, defaultMS int) int {
+	if ms, ok := t[apiName]; ok && ms > 0 {
+		return ms
+	}
+	return defaultMS
+}
+
+// defaultAPITimeouts builds the name-keyed replacement for what used to be
+// a make([]int, 38) indexed by API key: every known API gets timeoutMS,
+// with the handful of APIs that need a different default overridden
+// explicitly.
+func defaultAPITimeouts(timeoutMS int, sessionTimeoutMS int32, fetchMaxWaitMS int32) APITimeouts {
+	timeouts := make(APITimeouts, len(apiKeyByName))
+	for name := range apiKeyByName {
+		timeouts[name] = timeoutMS
+	}
+	timeouts["JoinGroup"] = int(sessionTimeoutMS) + 5000
+	timeouts["OffsetCommit"] = int(sessionTimeoutMS) / 2
+	timeouts["Fetch"] = timeoutMS + int(fetchMaxWaitMS)
+	return timeouts
+}
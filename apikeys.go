@@ -0,0 +1,68 @@
+package healer
+
+// apiKeyByName is the canonical name<->key registry for Kafka API keys,
+// shared by the config layer (TimeoutMSForAPI) and Broker.Request so
+// neither has to know numeric offsets. It only needs to grow when a new API
+// key is actually used somewhere in this client; unlike a fixed-length
+// slice indexed by key, adding one here can never silently corrupt an
+// unrelated API's timeout.
+var apiKeyByName = map[string]int16{
+	"Produce":              0,
+	"Fetch":                1,
+	"ListOffsets":          2,
+	"Metadata":             3,
+	"LeaderAndIsr":         4,
+	"StopReplica":          5,
+	"UpdateMetadata":       6,
+	"ControlledShutdown":   7,
+	"OffsetCommit":         8,
+	"OffsetFetch":          9,
+	"FindCoordinator":      10,
+	"JoinGroup":            11,
+	"Heartbeat":            12,
+	"LeaveGroup":           13,
+	"SyncGroup":            14,
+	"DescribeGroups":       15,
+	"ListGroups":           16,
+	"SaslHandshake":        17,
+	"ApiVersions":          18,
+	"CreateTopics":         19,
+	"DeleteTopics":         20,
+	"DeleteRecords":        21,
+	"InitProducerId":       22,
+	"OffsetForLeaderEpoch": 23,
+	"AddPartitionsToTxn":   24,
+	"AddOffsetsToTxn":      25,
+	"EndTxn":               26,
+	"WriteTxnMarkers":      27,
+	"TxnOffsetCommit":      28,
+	"DescribeAcls":         29,
+	"CreateAcls":           30,
+	"DeleteAcls":           31,
+	"DescribeConfigs":      32,
+	"AlterConfigs":         33,
+	"AlterReplicaLogDirs":  34,
+	"DescribeLogDirs":      35,
+	"SaslAuthenticate":     36,
+	"CreatePartitions":     37,
+}
+
+var apiNameByKey = func() map[int16]string {
+	byKey := make(map[int16]string, len(apiKeyByName))
+	for name, key := range apiKeyByName {
+		byKey[key] = name
+	}
+	return byKey
+}()
+
+// APIName returns the canonical name for a Kafka API key, or "" if key is
+// not in the registry.
+func APIName(key int16) string {
+	return apiNameByKey[key]
+}
+
+// APIKeyByName returns the Kafka API key for name and whether it was found.
+func APIKeyByName(name string) (int16, bool) {
+	key, ok := apiKeyByName[name]
+	return key, ok
+}
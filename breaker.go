@@ -0,0 +1,90 @@
+package healer
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrBreakerOpen is returned by Breaker.Run instead of calling work when the
+// breaker is open, so callers fail fast against a broker that is down
+// rather than block on another doomed request.
+var ErrBreakerOpen = errors.New("circuit breaker is open")
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// Breaker is a minimal circuit breaker in the style of
+// eapache/go-resiliency/breaker: it opens after a run of consecutive
+// failures, lets a single probe through once the reset timeout elapses, and
+// closes again on the probe's success.
+type Breaker struct {
+	mu sync.Mutex
+
+	state            breakerState
+	consecutiveFails int
+	failureThreshold int
+	resetTimeout     time.Duration
+	openedAt         time.Time
+}
+
+// NewBreaker returns a Breaker that opens after failureThreshold
+// consecutive failures and allows a half-open probe resetTimeout after
+// opening.
+func NewBreaker(failureThreshold int, resetTimeout time.Duration) *Breaker {
+	return &Breaker{
+		failureThreshold: failureThreshold,
+		resetTimeout:     resetTimeout,
+	}
+}
+
+// Run calls work if the breaker allows it, and records the outcome.
+func (b *Breaker) Run(work func() error) error {
+	if !b.ready() {
+		return ErrBreakerOpen
+	}
+
+	err := work()
+	b.record(err == nil)
+	return err
+}
+
+func (b *Breaker) ready() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerClosed:
+		return true
+	case breakerOpen:
+		if time.Since(b.openedAt) < b.resetTimeout {
+			return false
+		}
+		b.state = breakerHalfOpen
+		return true
+	default: // breakerHalfOpen: let exactly one probe proceed at a time
+		return true
+	}
+}
+
+func (b *Breaker) record(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if success {
+		b.state = breakerClosed
+		b.consecutiveFails = 0
+		return
+	}
+
+	b.consecutiveFails++
+	if b.state == breakerHalfOpen || b.consecutiveFails >= b.failureThreshold {
+		b.state = breakerOpen
+		b.openedAt = time.Now()
+	}
+}
@@ -0,0 +1,282 @@
+package healer
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// the subset of the protocol needed to authenticate a connection before any
+// other Kafka request is allowed to go out on it.
+const (
+	saslHandshakeAPIKey    = 17
+	saslAuthenticateAPIKey = 36
+)
+
+// Broker wraps a single TCP connection to one kafka node. Connections are
+// established plaintext, then optionally upgraded to TLS and/or
+// authenticated with SASL before the broker is handed back to callers.
+type Broker struct {
+	address  string
+	clientID string
+	config   *BrokerConfig
+
+	conn          net.Conn
+	reader        *bufio.Reader
+	correlationID uint32
+}
+
+var (
+	errUnsupportedSaslMechanism = errors.New("sasl mechanism not supported by this client")
+)
+
+// NewBroker dials address, performs the TLS handshake and SASL
+// handshake/authenticate exchange required by config.Security (if any), and
+// returns a Broker ready to carry normal Kafka requests. clientID is only
+// used to tag tracing spans and is not sent on the wire here.
+func NewBroker(address, clientID string, config *BrokerConfig) (*Broker, error) {
+	if config == nil {
+		config = DefaultBrokerConfig()
+	}
+
+	conn, err := net.DialTimeout("tcp", address, time.Duration(config.ConnectTimeoutMS)*time.Millisecond)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Broker{
+		address:  address,
+		clientID: clientID,
+		config:   config,
+		conn:     conn,
+		reader:   bufio.NewReader(conn),
+	}
+
+	if err := b.upgradeTLS(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if err := b.authenticateSasl(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// upgradeTLS wraps b.conn in a *tls.Conn when config.Security enables SSL.
+func (b *Broker) upgradeTLS() error {
+	security := b.config.Security
+	if !security.usesTLS() {
+		return nil
+	}
+
+	tlsConfig, err := security.buildTLSConfig()
+	if err != nil {
+		return err
+	}
+
+	tlsConn := tls.Client(b.conn, tlsConfig)
+	if err := tlsConn.Handshake(); err != nil {
+		return fmt.Errorf("tls handshake with %s failed: %w", b.address, err)
+	}
+
+	b.conn = tlsConn
+	b.reader = bufio.NewReader(tlsConn)
+	return nil
+}
+
+// authenticateSasl runs SaslHandshake followed by the mechanism-specific
+// SaslAuthenticate frames, as required by config.Security.
+func (b *Broker) authenticateSasl() error {
+	security := b.config.Security
+	if !security.usesSasl() {
+		return nil
+	}
+
+	if err := b.sendSaslHandshake(security.Sasl.Mechanism); err != nil {
+		return err
+	}
+
+	switch security.Sasl.Mechanism {
+	case SaslMechanismPlain:
+		return b.authenticateSaslPlain(security.Sasl)
+	case SaslMechanismScramSha256, SaslMechanismScramSha512:
+		return b.authenticateSaslScram(security.Sasl)
+	case SaslMechanismGSSAPI:
+		return b.authenticateSaslGSSAPI(security.Sasl)
+	default:
+		return errUnsupportedSaslMechanism
+	}
+}
+
+func (b *Broker) sendSaslHandshake(mechanism string) error {
+	payload := make([]byte, 2+len(mechanism))
+	binary.BigEndian.PutUint16(payload[0:], uint16(len(mechanism)))
+	copy(payload[2:], mechanism)
+
+	_, err := b.requestRaw(saslHandshakeAPIKey, 0, payload)
+	return err
+}
+
+// encodeSaslAuthenticateRequest wraps authBytes in the BYTES-type framing
+// (INT32 length + data) SaslAuthenticateRequest's auth_bytes field uses.
+func encodeSaslAuthenticateRequest(authBytes []byte) []byte {
+	body := make([]byte, 4+len(authBytes))
+	binary.BigEndian.PutUint32(body[0:], uint32(len(authBytes)))
+	copy(body[4:], authBytes)
+	return body
+}
+
+// decodeSaslAuthenticateResponse parses a v0 SaslAuthenticateResponse:
+// error_code(INT16) error_message(NULLABLE_STRING) auth_bytes(BYTES). It
+// returns auth_bytes, or the broker-reported error as error_message (falling
+// back to getErrorFromErrorCode) when error_code is non-zero.
+func decodeSaslAuthenticateResponse(payload []byte) ([]byte, error) {
+	if len(payload) < 2 {
+		return nil, fmt.Errorf("SaslAuthenticate response too short: %d bytes", len(payload))
+	}
+	offset := 0
+	errorCode := int16(binary.BigEndian.Uint16(payload[offset:]))
+	offset += 2
+
+	messageLen := int16(binary.BigEndian.Uint16(payload[offset:]))
+	offset += 2
+	var errorMessage string
+	if messageLen >= 0 {
+		errorMessage = string(payload[offset : offset+int(messageLen)])
+		offset += int(messageLen)
+	}
+
+	if errorCode != 0 {
+		if errorMessage != "" {
+			return nil, fmt.Errorf("SaslAuthenticate failed: %s", errorMessage)
+		}
+		return nil, getErrorFromErrorCode(errorCode)
+	}
+
+	authBytesLen := binary.BigEndian.Uint32(payload[offset:])
+	offset += 4
+	return payload[offset : offset+int(authBytesLen)], nil
+}
+
+func (b *Broker) authenticateSaslPlain(sasl *SaslConfig) error {
+	// RFC 4616: authzid(empty) NUL authcid NUL passwd
+	msg := "\x00" + sasl.Username + "\x00" + sasl.Password
+	resp, err := b.requestRaw(saslAuthenticateAPIKey, 0, encodeSaslAuthenticateRequest([]byte(msg)))
+	if err != nil {
+		return err
+	}
+	_, err = decodeSaslAuthenticateResponse(resp)
+	return err
+}
+
+// authenticateSaslGSSAPI drives the SASL/GSSAPI token exchange: it sends
+// whatever token sasl.GSSAPIInitSecContext/GSSAPIContinueSecContext
+// produce as consecutive SaslAuthenticate frames until the caller-supplied
+// Kerberos client reports the security context is established. The
+// standard library has no Kerberos client, so one must be wired in via
+// those callbacks (see the SaslConfig doc comment).
+func (b *Broker) authenticateSaslGSSAPI(sasl *SaslConfig) error {
+	if sasl.GSSAPIInitSecContext == nil || sasl.GSSAPIContinueSecContext == nil {
+		return fmt.Errorf("%w: GSSAPI requires SaslConfig.GSSAPIInitSecContext and GSSAPIContinueSecContext to be set to a Kerberos client", errUnsupportedSaslMechanism)
+	}
+
+	token, err := sasl.GSSAPIInitSecContext()
+	if err != nil {
+		return err
+	}
+
+	for {
+		resp, err := b.requestRaw(saslAuthenticateAPIKey, 0, encodeSaslAuthenticateRequest(token))
+		if err != nil {
+			return err
+		}
+		serverToken, err := decodeSaslAuthenticateResponse(resp)
+		if err != nil {
+			return err
+		}
+
+		var done bool
+		token, done, err = sasl.GSSAPIContinueSecContext(serverToken)
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+	}
+}
+
+// RequestWithContext issues apiName/apiKey/apiVersion with body and returns
+// the raw response payload, wrapping the call in a "kafka.<apiName>" span
+// per the tracing convention shared by Request*, producer send, consumer
+// fetch and metadata refresh. topic/partition may be left empty/zero for
+// requests that aren't about one partition (e.g. Metadata).
+func (b *Broker) RequestWithContext(ctx context.Context, apiName string, apiKey, apiVersion int16, body []byte, topic string, partition int32) ([]byte, error) {
+	_, span := startAPISpan(ctx, apiName, apiKey, apiVersion, b.correlationID+1, b.clientID, b.address)
+
+	timeout := time.Duration(b.config.timeoutMSFor(apiName)) * time.Millisecond
+	b.conn.SetDeadline(time.Now().Add(timeout))
+
+	resp, err := b.requestRaw(apiKey, apiVersion, body)
+	finishAPISpan(span, topic, partition, err)
+	return resp, err
+}
+
+// requestRaw writes a full Kafka RequestMessage (api_key, api_version,
+// correlation_id, client_id, then body) and returns the raw response
+// payload.
+func (b *Broker) requestRaw(apiKey, apiVersion int16, body []byte) ([]byte, error) {
+	b.correlationID++
+
+	clientID := []byte(b.clientID)
+	headerLen := 2 + 2 + 4 + 2 + len(clientID) // api_key + api_version + correlation_id + client_id length + client_id
+	requestLen := headerLen + len(body)
+
+	req := make([]byte, 4+requestLen)
+	binary.BigEndian.PutUint32(req[0:], uint32(requestLen))
+	binary.BigEndian.PutUint16(req[4:], uint16(apiKey))
+	binary.BigEndian.PutUint16(req[6:], uint16(apiVersion))
+	binary.BigEndian.PutUint32(req[8:], b.correlationID)
+	binary.BigEndian.PutUint16(req[12:], uint16(len(clientID)))
+	copy(req[14:], clientID)
+	copy(req[14+len(clientID):], body)
+
+	if _, err := b.conn.Write(req); err != nil {
+		return nil, err
+	}
+
+	sizeBuf := make([]byte, 4)
+	if _, err := io.ReadFull(b.reader, sizeBuf); err != nil {
+		return nil, err
+	}
+	size := binary.BigEndian.Uint32(sizeBuf)
+	resp := make([]byte, size)
+	if _, err := io.ReadFull(b.reader, resp); err != nil {
+		return nil, err
+	}
+	if len(resp) < 4 {
+		return nil, fmt.Errorf("response too short to contain a correlation id: %d bytes", len(resp))
+	}
+	respCorrelationID := binary.BigEndian.Uint32(resp[0:])
+	if respCorrelationID != b.correlationID {
+		return nil, fmt.Errorf("correlation id mismatch: sent %d, got %d", b.correlationID, respCorrelationID)
+	}
+	// Every per-API decoder (NewMetadataResponse, decodeFindCoordinatorResponse,
+	// decodeProduceResponse, gokafka.DecodeFetchResponse, ...) expects the
+	// correlation id already stripped, matching how it reads the rest of the
+	// response body.
+	return resp[4:], nil
+}
+
+func (b *Broker) Close() error {
+	return b.conn.Close()
+}
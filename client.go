@@ -0,0 +1,439 @@
+package healer
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	apiKeyMetadata        = 3
+	apiKeyFindCoordinator = 10
+)
+
+// maxNegativeCacheEntries bounds the UnknownTopicOrPartition negative cache
+// so a client that keeps probing many bad topic names can't grow it
+// unboundedly; the oldest entry is evicted once the cap is hit.
+const maxNegativeCacheEntries = 1024
+
+// negativeCacheTTL is how long a topic is remembered as unknown before the
+// client is willing to ask the cluster about it again.
+const negativeCacheTTL = 60 * time.Second
+
+var errUnknownTopicOrPartition = errors.New("unknown topic or partition (negative cache)")
+
+// Client owns a pool of broker connections shared between producers,
+// consumers and admin utilities, and the metadata cache/refresh loop that
+// lets them avoid independently redialing bootstrap servers, comparable to
+// Sarama's Client.
+type Client struct {
+	clientID  string
+	config    *BrokerConfig
+	bootstrap []string
+
+	mu       sync.RWMutex
+	brokers  map[int32]*Broker // by node id
+	metadata *MetadataResponse
+
+	usedTopicsMu sync.Mutex
+	usedTopics   map[string]struct{}
+
+	negativeMu    sync.Mutex
+	negativeCache map[string]time.Time
+	negativeOrder []string
+
+	closing chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewClient dials bootstrapServers (a comma-separated host:port list),
+// fetches initial metadata, and starts the periodic refresh loop driven by
+// config.MetadataRefreshIntervalMS.
+func NewClient(bootstrapServers, clientID string, config *BrokerConfig) (*Client, error) {
+	if config == nil {
+		config = DefaultBrokerConfig()
+	}
+	if err := config.checkValid(); err != nil {
+		return nil, err
+	}
+	if bootstrapServers == "" {
+		return nil, brokerAddressNotSet
+	}
+
+	c := &Client{
+		clientID:      clientID,
+		config:        config,
+		bootstrap:     strings.Split(bootstrapServers, ","),
+		brokers:       make(map[int32]*Broker),
+		usedTopics:    make(map[string]struct{}),
+		negativeCache: make(map[string]time.Time),
+		closing:       make(chan struct{}),
+	}
+
+	if err := c.RefreshMetadata(); err != nil {
+		return nil, err
+	}
+
+	c.wg.Add(1)
+	go c.refreshLoop()
+
+	return c, nil
+}
+
+func (c *Client) refreshLoop() {
+	defer c.wg.Done()
+	interval := time.Duration(c.config.MetadataRefreshIntervalMS) * time.Millisecond
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.RefreshMetadata()
+		case <-c.closing:
+			return
+		}
+	}
+}
+
+func (c *Client) Close() error {
+	close(c.closing)
+	c.wg.Wait()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var firstErr error
+	for _, b := range c.brokers {
+		if err := b.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// anyBroker returns any already-connected broker, falling back to dialing
+// the bootstrap list, so metadata/coordinator lookups always have somewhere
+// to ask.
+func (c *Client) anyBroker() (*Broker, error) {
+	c.mu.RLock()
+	for _, b := range c.brokers {
+		c.mu.RUnlock()
+		return b, nil
+	}
+	c.mu.RUnlock()
+
+	var lastErr error
+	for _, addr := range c.bootstrap {
+		b, err := NewBroker(strings.TrimSpace(addr), c.clientID, c.config)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return b, nil
+	}
+	return nil, lastErr
+}
+
+// brokerForNode returns the pooled connection for nodeID, dialing and
+// caching one if this is the first time it's needed.
+func (c *Client) brokerForNode(nodeID int32) (*Broker, error) {
+	c.mu.RLock()
+	b, ok := c.brokers[nodeID]
+	c.mu.RUnlock()
+	if ok {
+		return b, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if b, ok := c.brokers[nodeID]; ok {
+		return b, nil
+	}
+
+	for _, info := range c.metadataSnapshot().Brokers {
+		if info.NodeId != nodeID {
+			continue
+		}
+		addr := hostPort(info.Host, info.Port)
+		b, err := NewBroker(addr, c.clientID, c.config)
+		if err != nil {
+			return nil, err
+		}
+		c.brokers[nodeID] = b
+		return b, nil
+	}
+
+	return nil, errors.New("no broker info for node id")
+}
+
+func (c *Client) metadataSnapshot() *MetadataResponse {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.metadata
+}
+
+// RefreshMetadata fetches fresh metadata for topics (or, if topics is
+// empty, for every topic currently in use by a live producer/consumer,
+// rather than every topic in the cluster).
+func (c *Client) RefreshMetadata(topics ...string) error {
+	if len(topics) == 0 {
+		topics = c.liveTopics()
+	} else {
+		c.markTopicsUsed(topics)
+	}
+
+	broker, err := c.anyBroker()
+	if err != nil {
+		return err
+	}
+
+	resp, err := broker.RequestWithContext(context.Background(), "Metadata", apiKeyMetadata, 0, encodeMetadataRequest(topics), "", 0)
+	if err != nil {
+		return err
+	}
+
+	metadataResponse, err := NewMetadataResponse(resp)
+	if err != nil && metadataResponse == nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.metadata = metadataResponse
+	c.mu.Unlock()
+
+	for _, t := range metadataResponse.TopicMetadatas {
+		if t.TopicErrorCode == 0 {
+			c.clearNegative(t.TopicName)
+		}
+	}
+
+	return nil
+}
+
+func (c *Client) liveTopics() []string {
+	c.usedTopicsMu.Lock()
+	defer c.usedTopicsMu.Unlock()
+	topics := make([]string, 0, len(c.usedTopics))
+	for t := range c.usedTopics {
+		topics = append(topics, t)
+	}
+	return topics
+}
+
+func (c *Client) markTopicsUsed(topics []string) {
+	c.usedTopicsMu.Lock()
+	defer c.usedTopicsMu.Unlock()
+	for _, t := range topics {
+		c.usedTopics[t] = struct{}{}
+	}
+}
+
+func (c *Client) findTopic(topic string) *TopicMetadata {
+	meta := c.metadataSnapshot()
+	if meta == nil {
+		return nil
+	}
+	for _, t := range meta.TopicMetadatas {
+		if t.TopicName == topic {
+			return t
+		}
+	}
+	return nil
+}
+
+// Leader returns the broker currently acting as leader for (topic,
+// partition), consulting the negative cache first so a run of lookups
+// against a nonexistent topic doesn't hammer the cluster.
+func (c *Client) Leader(topic string, partition int32) (*Broker, error) {
+	if c.isNegative(topic) {
+		return nil, errUnknownTopicOrPartition
+	}
+
+	t := c.findTopic(topic)
+	if t == nil {
+		if err := c.RefreshMetadata(topic); err != nil {
+			return nil, err
+		}
+		t = c.findTopic(topic)
+	}
+	if t == nil || t.TopicErrorCode != 0 {
+		c.markNegative(topic)
+		return nil, errUnknownTopicOrPartition
+	}
+
+	for _, p := range t.PartitionMetadatas {
+		if p.PartitionID == partition {
+			return c.brokerForNode(p.Leader)
+		}
+	}
+
+	return nil, errors.New("unknown partition")
+}
+
+// WritablePartitions returns the partitions of topic that currently have a
+// live leader.
+func (c *Client) WritablePartitions(topic string) ([]int32, error) {
+	t := c.findTopic(topic)
+	if t == nil {
+		return nil, errUnknownTopicOrPartition
+	}
+	var partitions []int32
+	for _, p := range t.PartitionMetadatas {
+		if p.Leader != -1 {
+			partitions = append(partitions, p.PartitionID)
+		}
+	}
+	return partitions, nil
+}
+
+// Partitions returns every partition id of topic, regardless of whether it
+// currently has a leader.
+func (c *Client) Partitions(topic string) ([]int32, error) {
+	t := c.findTopic(topic)
+	if t == nil {
+		return nil, errUnknownTopicOrPartition
+	}
+	partitions := make([]int32, len(t.PartitionMetadatas))
+	for i, p := range t.PartitionMetadatas {
+		partitions[i] = p.PartitionID
+	}
+	return partitions, nil
+}
+
+// Topics returns every topic name known from the last metadata refresh.
+func (c *Client) Topics() []string {
+	meta := c.metadataSnapshot()
+	if meta == nil {
+		return nil
+	}
+	topics := make([]string, len(meta.TopicMetadatas))
+	for i, t := range meta.TopicMetadatas {
+		topics[i] = t.TopicName
+	}
+	return topics
+}
+
+// Controller is not implemented: the MetadataResponse version this client
+// decodes (see metadata_response.go) predates the ControllerId field.
+func (c *Client) Controller() (*Broker, error) {
+	return nil, errors.New("controller lookup requires a newer MetadataResponse version than this client decodes")
+}
+
+// Coordinator issues FindCoordinator for groupID and returns the broker
+// that owns it.
+func (c *Client) Coordinator(groupID string) (*Broker, error) {
+	broker, err := c.anyBroker()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := broker.RequestWithContext(context.Background(), "FindCoordinator", apiKeyFindCoordinator, 0, encodeFindCoordinatorRequest(groupID), "", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	nodeID, host, port, err := decodeFindCoordinatorResponse(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	b, ok := c.brokers[nodeID]
+	c.mu.RUnlock()
+	if ok {
+		return b, nil
+	}
+
+	addr := hostPort(host, port)
+	b, err = NewBroker(addr, c.clientID, c.config)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.brokers[nodeID] = b
+	c.mu.Unlock()
+	return b, nil
+}
+
+func (c *Client) isNegative(topic string) bool {
+	c.negativeMu.Lock()
+	defer c.negativeMu.Unlock()
+	t, ok := c.negativeCache[topic]
+	if !ok {
+		return false
+	}
+	if time.Since(t) > negativeCacheTTL {
+		delete(c.negativeCache, topic)
+		return false
+	}
+	return true
+}
+
+func (c *Client) markNegative(topic string) {
+	c.negativeMu.Lock()
+	defer c.negativeMu.Unlock()
+	if _, ok := c.negativeCache[topic]; !ok {
+		if len(c.negativeOrder) >= maxNegativeCacheEntries {
+			oldest := c.negativeOrder[0]
+			c.negativeOrder = c.negativeOrder[1:]
+			delete(c.negativeCache, oldest)
+		}
+		c.negativeOrder = append(c.negativeOrder, topic)
+	}
+	c.negativeCache[topic] = time.Now()
+}
+
+func (c *Client) clearNegative(topic string) {
+	c.negativeMu.Lock()
+	defer c.negativeMu.Unlock()
+	delete(c.negativeCache, topic)
+}
+
+// encodeMetadataRequest builds a Metadata request body for topics (empty
+// means "all topics").
+func encodeMetadataRequest(topics []string) []byte {
+	buf := make([]byte, 4)
+	binary.BigEndian.PutUint32(buf, uint32(len(topics)))
+	for _, t := range topics {
+		l := make([]byte, 2)
+		binary.BigEndian.PutUint16(l, uint16(len(t)))
+		buf = append(buf, l...)
+		buf = append(buf, t...)
+	}
+	return buf
+}
+
+func encodeFindCoordinatorRequest(groupID string) []byte {
+	buf := make([]byte, 2)
+	binary.BigEndian.PutUint16(buf, uint16(len(groupID)))
+	buf = append(buf, groupID...)
+	return buf
+}
+
+func decodeFindCoordinatorResponse(payload []byte) (nodeID int32, host string, port int32, err error) {
+	offset := 0
+	errorCode := int16(binary.BigEndian.Uint16(payload[offset:]))
+	offset += 2
+	if errorCode != 0 {
+		return 0, "", 0, getErrorFromErrorCode(errorCode)
+	}
+
+	nodeID = int32(binary.BigEndian.Uint32(payload[offset:]))
+	offset += 4
+	hostLen := int(binary.BigEndian.Uint16(payload[offset:]))
+	offset += 2
+	host = string(payload[offset : offset+hostLen])
+	offset += hostLen
+	port = int32(binary.BigEndian.Uint32(payload[offset:]))
+	return nodeID, host, port, nil
+}
+
+func hostPort(host string, port int32) string {
+	return net.JoinHostPort(host, strconv.Itoa(int(port)))
+}
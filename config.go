@@ -6,18 +6,20 @@ import (
 )
 
 type BrokerConfig struct {
-	ConnectTimeoutMS          int   `json:"connect.timeout.ms"`
-	TimeoutMS                 int   `json:"timeout.ms"`
-	TimeoutMSForEachAPI       []int `json:"timeout.ms.for.eachapi"`
-	MetadataRefreshIntervalMS int   `json:"metadata.refresh.interval.ms"`
+	ConnectTimeoutMS          int             `json:"connect.timeout.ms"`
+	TimeoutMS                 int             `json:"timeout.ms"`
+	TimeoutMSForAPI           APITimeouts     `json:"timeout.ms.for.eachapi"`
+	MetadataRefreshIntervalMS int             `json:"metadata.refresh.interval.ms"`
+	Security                  *SecurityConfig `json:"security"`
 }
 
 func DefaultBrokerConfig() *BrokerConfig {
 	return &BrokerConfig{
 		ConnectTimeoutMS:          60000,
 		TimeoutMS:                 30000,
-		TimeoutMSForEachAPI:       make([]int, 0),
+		TimeoutMSForAPI:           make(APITimeouts),
 		MetadataRefreshIntervalMS: 300 * 1000,
+		Security:                 DefaultSecurityConfig(),
 	}
 }
 
@@ -25,36 +27,51 @@ func getBrokerConfigFromConsumerConfig(c *ConsumerConfig) *BrokerConfig {
 	b := DefaultBrokerConfig()
 	b.ConnectTimeoutMS = c.ConnectTimeoutMS
 	b.TimeoutMS = c.TimeoutMS
-	b.TimeoutMSForEachAPI = c.TimeoutMSForEachAPI
+	b.TimeoutMSForAPI = c.TimeoutMSForAPI
+	b.Security = c.Security
 	return b
 }
 
+// timeoutMSFor returns the request timeout Broker.Request should use for
+// apiName, falling back to TimeoutMS when apiName has no specific entry.
+func (c *BrokerConfig) timeoutMSFor(apiName string) int {
+	return c.TimeoutMSForAPI.timeoutMSFor(apiName, c.TimeoutMS)
+}
+
 var (
 	brokerAddressNotSet = errors.New("broker address not set in broker config")
 )
 
 func (c *BrokerConfig) checkValid() error {
-	return nil
+	return c.Security.checkValid()
 }
 
 type ConsumerConfig struct {
-	BootstrapServers     string `json:"bootstrap.servers"`
-	ClientID             string `json:"client.id"`
-	GroupID              string `json:"group.id"`
-	RetryBackOffMS       int    `json:"retry.backoff.ms"`
-	MetadataMaxAgeMS     int    `json:"metadata.max.age.ms"`
-	SessionTimeoutMS     int32  `json:"session.timeout.ms"`
-	FetchMaxWaitMS       int32  `json:"fetch.max.wait.ms"`
-	FetchMaxBytes        int32  `json:"fetch.max.bytes"`
-	FetchMinBytes        int32  `json:"fetch.min.bytes"`
-	FromBeginning        bool   `json:"frombeginning"`
-	AutoCommit           bool   `json:"auto.commit"`
-	CommitAfterFetch     bool   `json:"commit.after.fetch"`
-	AutoCommitIntervalMS int    `json:"auto.commit.interval.ms"`
-	OffsetsStorage       int    `json:"offsets.storage"`
-	ConnectTimeoutMS     int    `json:"connect.timeout.ms"`
-	TimeoutMS            int    `json:"timeout.ms"`
-	TimeoutMSForEachAPI  []int  `json:"timeout.ms.for.eachapi"`
+	BootstrapServers     string      `json:"bootstrap.servers"`
+	ClientID             string      `json:"client.id"`
+	GroupID              string      `json:"group.id"`
+	RetryBackOffMS       int         `json:"retry.backoff.ms"`
+	MetadataMaxAgeMS     int         `json:"metadata.max.age.ms"`
+	SessionTimeoutMS     int32       `json:"session.timeout.ms"`
+	FetchMaxWaitMS       int32       `json:"fetch.max.wait.ms"`
+	FetchMaxBytes        int32       `json:"fetch.max.bytes"`
+	FetchMinBytes        int32       `json:"fetch.min.bytes"`
+	FromBeginning        bool        `json:"frombeginning"`
+	AutoCommit           bool        `json:"auto.commit"`
+	CommitAfterFetch     bool        `json:"commit.after.fetch"`
+	AutoCommitIntervalMS int         `json:"auto.commit.interval.ms"`
+	OffsetsStorage       int         `json:"offsets.storage"`
+	ConnectTimeoutMS     int         `json:"connect.timeout.ms"`
+	TimeoutMS            int         `json:"timeout.ms"`
+	TimeoutMSForAPI      APITimeouts `json:"timeout.ms.for.eachapi"`
+
+	Security *SecurityConfig `json:"security"`
+}
+
+// timeoutMSFor returns the request timeout Broker.Request should use for
+// apiName, falling back to TimeoutMS when apiName has no specific entry.
+func (c *ConsumerConfig) timeoutMSFor(apiName string) int {
+	return c.TimeoutMSForAPI.timeoutMSFor(apiName, c.TimeoutMS)
 }
 
 func DefaultConsumerConfig() *ConsumerConfig {
@@ -74,17 +91,10 @@ func DefaultConsumerConfig() *ConsumerConfig {
 		OffsetsStorage:       1,
 		ConnectTimeoutMS:     30000,
 		TimeoutMS:            30000,
+		Security:             DefaultSecurityConfig(),
 	}
 
-	if c.TimeoutMSForEachAPI == nil {
-		c.TimeoutMSForEachAPI = make([]int, 38)
-		for i := range c.TimeoutMSForEachAPI {
-			c.TimeoutMSForEachAPI[i] = c.TimeoutMS
-		}
-		c.TimeoutMSForEachAPI[API_JoinGroup] = int(c.SessionTimeoutMS) + 5000
-		c.TimeoutMSForEachAPI[API_OffsetCommitRequest] = int(c.SessionTimeoutMS) / 2
-		c.TimeoutMSForEachAPI[API_FetchRequest] = c.TimeoutMS + int(c.FetchMaxWaitMS)
-	}
+	c.TimeoutMSForAPI = defaultAPITimeouts(c.TimeoutMS, c.SessionTimeoutMS, c.FetchMaxWaitMS)
 
 	return c
 }
@@ -101,14 +111,8 @@ func GetConsumerConfig(config map[string]interface{}) (*ConsumerConfig, error) {
 		return nil, err
 	}
 
-	if c.TimeoutMSForEachAPI == nil {
-		c.TimeoutMSForEachAPI = make([]int, 38)
-		for i := range c.TimeoutMSForEachAPI {
-			c.TimeoutMSForEachAPI[i] = c.TimeoutMS
-		}
-		c.TimeoutMSForEachAPI[API_JoinGroup] = int(c.SessionTimeoutMS) + 5000
-		c.TimeoutMSForEachAPI[API_OffsetCommitRequest] = int(c.SessionTimeoutMS) / 2
-		c.TimeoutMSForEachAPI[API_FetchRequest] = c.TimeoutMS + int(c.FetchMaxWaitMS)
+	if c.TimeoutMSForAPI == nil {
+		c.TimeoutMSForAPI = defaultAPITimeouts(c.TimeoutMS, c.SessionTimeoutMS, c.FetchMaxWaitMS)
 	}
 
 	return c, nil
@@ -129,7 +133,7 @@ func (config *ConsumerConfig) checkValid() error {
 	if config.OffsetsStorage != 0 && config.OffsetsStorage != 1 {
 		return invallidOffsetsStorageConfig
 	}
-	return nil
+	return config.Security.checkValid()
 }
 
 type ProducerConfig struct {
@@ -144,6 +148,8 @@ type ProducerConfig struct {
 	FetchTopicMetaDataRetrys int    `json:"fetch.topic.metadata.retrys"`
 	ConnectionsMaxIdleMS     int    `json:"connections.max.idle.ms"`
 
+	Security *SecurityConfig `json:"security"`
+
 	// TODO
 	Retries          int   `json:"retries"`
 	RequestTimeoutMS int32 `json:"request.timeout.ms"`
@@ -160,6 +166,7 @@ func DefaultProducerConfig() *ProducerConfig {
 		MetadataMaxAgeMS:         300000,
 		FetchTopicMetaDataRetrys: 3,
 		ConnectionsMaxIdleMS:     540000,
+		Security:                 DefaultSecurityConfig(),
 
 		Retries:          0,
 		RequestTimeoutMS: 30000,
@@ -192,5 +199,5 @@ func (config *ProducerConfig) checkValid() error {
 	default:
 		return unknownCompressionType
 	}
-	return nil
+	return config.Security.checkValid()
 }
@@ -0,0 +1,102 @@
+package healer
+
+import (
+	"context"
+	"encoding/binary"
+
+	"github.com/sundy-li/healer/gokafka"
+)
+
+const apiKeyFetch = 1
+
+// ConsumerMessage is the client-facing view of one fetched record. It
+// carries the v2 (RecordBatch) Headers/Timestamp fields through from
+// gokafka.Message so callers that need trace-context propagation (see
+// Tracer.Extract) or per-record timestamps don't have to reach into the
+// gokafka package themselves.
+type ConsumerMessage struct {
+	Topic     string
+	Partition int32
+	Offset    int64
+	Key       []byte
+	Value     []byte
+	Headers   []gokafka.MessageHeader
+	Timestamp int64
+}
+
+// Fetch issues a single Fetch request for (topic, partition) starting at
+// offset and returns the decoded records.
+func (c *Client) Fetch(topic string, partition int32, offset int64, maxBytes int32) ([]*ConsumerMessage, error) {
+	broker, err := c.Leader(topic, partition)
+	if err != nil {
+		return nil, err
+	}
+
+	body := encodeFetchRequest(topic, partition, offset, maxBytes)
+	resp, err := broker.RequestWithContext(context.Background(), "Fetch", apiKeyFetch, 0, body, topic, partition)
+	if err != nil {
+		return nil, err
+	}
+
+	fetchResponse, err := gokafka.DecodeFetchResponse(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []*ConsumerMessage
+	for _, topicData := range fetchResponse {
+		for _, partitionData := range topicData.TopicDatas {
+			if partitionData.ErrorCode != 0 {
+				return nil, getErrorFromErrorCode(partitionData.ErrorCode)
+			}
+			for _, msg := range partitionData.MessageSet {
+				messages = append(messages, &ConsumerMessage{
+					Topic:     topicData.TopicName,
+					Partition: partitionData.Partition,
+					Offset:    msg.Offset,
+					Key:       msg.Key,
+					Value:     msg.Value,
+					Headers:   msg.Headers,
+					Timestamp: msg.Timestamp,
+				})
+			}
+		}
+	}
+	return messages, nil
+}
+
+// encodeFetchRequest builds a Fetch request body for a single (topic,
+// partition), the consumer-side mirror of encodeProduceRequest.
+func encodeFetchRequest(topic string, partition int32, offset int64, maxBytes int32) []byte {
+	buf := make([]byte, 12)                         // ReplicaId(-1) + MaxWaitTimeMS + MinBytes
+	binary.BigEndian.PutUint32(buf[0:], ^uint32(0)) // -1: not a replica broker
+	binary.BigEndian.PutUint32(buf[4:], 100)
+	binary.BigEndian.PutUint32(buf[8:], 1)
+
+	topicsCount := make([]byte, 4)
+	binary.BigEndian.PutUint32(topicsCount, 1)
+	buf = append(buf, topicsCount...)
+
+	topicLen := make([]byte, 2)
+	binary.BigEndian.PutUint16(topicLen, uint16(len(topic)))
+	buf = append(buf, topicLen...)
+	buf = append(buf, topic...)
+
+	partitionsCount := make([]byte, 4)
+	binary.BigEndian.PutUint32(partitionsCount, 1)
+	buf = append(buf, partitionsCount...)
+
+	partitionBuf := make([]byte, 4)
+	binary.BigEndian.PutUint32(partitionBuf, uint32(partition))
+	buf = append(buf, partitionBuf...)
+
+	offsetBuf := make([]byte, 8)
+	binary.BigEndian.PutUint64(offsetBuf, uint64(offset))
+	buf = append(buf, offsetBuf...)
+
+	maxBytesBuf := make([]byte, 4)
+	binary.BigEndian.PutUint32(maxBytesBuf, uint32(maxBytes))
+	buf = append(buf, maxBytesBuf...)
+
+	return buf
+}
@@ -0,0 +1,54 @@
+package healer
+
+import "fmt"
+
+// Kafka protocol error codes, as carried in ErrorCode fields of Metadata,
+// Produce, Fetch and FindCoordinator responses. Only the codes this client
+// currently branches on (directly, or via isRetriableError) get a dedicated
+// sentinel; everything else falls back to a generic error carrying the code.
+const (
+	errCodeUnknownServerError      = -1
+	errCodeOffsetOutOfRange        = 1
+	errCodeCorruptMessage          = 2
+	errCodeUnknownTopicOrPartition = 3
+	errCodeInvalidFetchSize        = 4
+	errCodeLeaderNotAvailable      = 5
+	errCodeNotLeaderForPartition   = 6
+	errCodeRequestTimedOut         = 7
+	errCodeNetworkException        = 13
+)
+
+var (
+	UnknownServerError      = fmt.Errorf("kafka error code %d: unknown server error", errCodeUnknownServerError)
+	OffsetOutOfRange        = fmt.Errorf("kafka error code %d: offset out of range", errCodeOffsetOutOfRange)
+	CorruptMessage          = fmt.Errorf("kafka error code %d: corrupt message", errCodeCorruptMessage)
+	UnknownTopicOrPartition = fmt.Errorf("kafka error code %d: unknown topic or partition", errCodeUnknownTopicOrPartition)
+	InvalidFetchSize        = fmt.Errorf("kafka error code %d: invalid fetch size", errCodeInvalidFetchSize)
+	LeaderNotAvailable      = fmt.Errorf("kafka error code %d: leader not available", errCodeLeaderNotAvailable)
+	NotLeaderForPartition   = fmt.Errorf("kafka error code %d: not leader for partition", errCodeNotLeaderForPartition)
+	RequestTimedOut         = fmt.Errorf("kafka error code %d: request timed out", errCodeRequestTimedOut)
+	NetworkException        = fmt.Errorf("kafka error code %d: network exception", errCodeNetworkException)
+
+	errorCodeTable = map[int16]error{
+		errCodeUnknownServerError:      UnknownServerError,
+		errCodeOffsetOutOfRange:        OffsetOutOfRange,
+		errCodeCorruptMessage:          CorruptMessage,
+		errCodeUnknownTopicOrPartition: UnknownTopicOrPartition,
+		errCodeInvalidFetchSize:        InvalidFetchSize,
+		errCodeLeaderNotAvailable:      LeaderNotAvailable,
+		errCodeNotLeaderForPartition:   NotLeaderForPartition,
+		errCodeRequestTimedOut:         RequestTimedOut,
+		errCodeNetworkException:        NetworkException,
+	}
+)
+
+// getErrorFromErrorCode maps a Kafka protocol ErrorCode to an error. code 0
+// (no error) is never expected to reach this function; callers check for it
+// before calling in. Codes without a dedicated sentinel above still produce
+// a usable error rather than losing the code.
+func getErrorFromErrorCode(code int16) error {
+	if err, ok := errorCodeTable[code]; ok {
+		return err
+	}
+	return fmt.Errorf("kafka error code %d", code)
+}
@@ -0,0 +1,90 @@
+package gokafka
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"io/ioutil"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+)
+
+var (
+	errCompressionNotSupported = errors.New("compression codec not supported yet")
+	errXerialFrameTruncated    = errors.New("truncated xerial-framed snappy payload")
+)
+
+// xerialSnappyMagic is the 8-byte header xerial-framed snappy streams start
+// with, as emitted by the Kafka server and every client library (the Java
+// client's own SnappyOutputStream follows the same framing).
+var xerialSnappyMagic = []byte{0x82, 'S', 'N', 'A', 'P', 'P', 'Y', 0}
+
+// decodeXerialSnappy unwraps a xerial-framed snappy stream: an 8-byte magic,
+// a 4-byte version and 4-byte "minimum compatible version", then a sequence
+// of INT32-length-prefixed blocks, each independently snappy-compressed.
+func decodeXerialSnappy(data []byte) ([]byte, error) {
+	const headerLen = len(xerialSnappyMagic) + 4 + 4
+	if len(data) < headerLen {
+		return nil, errXerialFrameTruncated
+	}
+
+	var out []byte
+	offset := headerLen
+	for offset < len(data) {
+		if offset+4 > len(data) {
+			return nil, errXerialFrameTruncated
+		}
+		blockLen := int(binary.BigEndian.Uint32(data[offset:]))
+		offset += 4
+		if blockLen < 0 || offset+blockLen > len(data) {
+			return nil, errXerialFrameTruncated
+		}
+
+		block, err := snappy.Decode(nil, data[offset:offset+blockLen])
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, block...)
+		offset += blockLen
+	}
+	return out, nil
+}
+
+// decompress applies the codec selected by the low 3 bits of a v0/v1
+// Attributes byte or a v2 RecordBatch Attributes field to data.
+func decompress(codec int8, data []byte) ([]byte, error) {
+	switch codec {
+	case CompressionNone:
+		return data, nil
+	case CompressionGzip:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return ioutil.ReadAll(r)
+	case CompressionSnappy:
+		// Real Kafka snappy payloads are xerial-framed (the broker and every
+		// client library, including the Java client, write this framing);
+		// only fall back to a single raw snappy block for the rare payload
+		// that isn't framed.
+		if bytes.HasPrefix(data, xerialSnappyMagic) {
+			return decodeXerialSnappy(data)
+		}
+		return snappy.Decode(nil, data)
+	case CompressionLZ4:
+		return ioutil.ReadAll(lz4.NewReader(bytes.NewReader(data)))
+	case CompressionZstd:
+		dec, err := zstd.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer dec.Close()
+		return ioutil.ReadAll(dec)
+	default:
+		return nil, errCompressionNotSupported
+	}
+}
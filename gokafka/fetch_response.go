@@ -66,41 +66,13 @@ func DecodeFetchResponse(payload []byte) (FetchResponse, error) {
 			offset += 8
 			fetchResponse[i].TopicDatas[j].MessageSetSize = int32(binary.BigEndian.Uint32(payload[offset:]))
 			offset += 4
-			fetchResponse[i].TopicDatas[j].MessageSet = make([]Message, fetchResponse[i].TopicDatas[j].MessageSetSize)
-			for k := int32(0); k < fetchResponse[i].TopicDatas[j].MessageSetSize; k++ {
-				fetchResponse[i].TopicDatas[j].MessageSet[k].Offset = int64(binary.BigEndian.Uint64(payload[offset:]))
-				offset += 8
-				fetchResponse[i].TopicDatas[j].MessageSet[k].MessageSize = int32(binary.BigEndian.Uint32(payload[offset:]))
-				offset += 4
-				fetchResponse[i].TopicDatas[j].MessageSet[k].Crc = binary.BigEndian.Uint32(payload[offset:])
-				offset += 4
-				fetchResponse[i].TopicDatas[j].MessageSet[k].MagicByte = int8(payload[offset])
-				offset += 1
-				fetchResponse[i].TopicDatas[j].MessageSet[k].Attributes = int8(payload[offset])
-				offset += 1
-				keyLength := int32(binary.BigEndian.Uint32(payload[offset:]))
-				offset += 4
-				if keyLength == -1 {
-					fetchResponse[i].TopicDatas[j].MessageSet[k].Key = nil
-				} else {
-					fetchResponse[i].TopicDatas[j].MessageSet[k].Key = make([]byte, keyLength)
-					copy(fetchResponse[i].TopicDatas[j].MessageSet[k].Key, payload[offset:offset+uint64(keyLength)])
-					offset += uint64(keyLength)
-				}
 
-				valueLength := int32(binary.BigEndian.Uint32(payload[offset:]))
-				offset += 4
-				if valueLength == -1 {
-					fetchResponse[i].TopicDatas[j].MessageSet[k].Value = nil
-				} else {
-					fetchResponse[i].TopicDatas[j].MessageSet[k].Value = make([]byte, valueLength)
-					copy(fetchResponse[i].TopicDatas[j].MessageSet[k].Value, payload[offset:offset+uint64(valueLength)])
-					offset += uint64(valueLength)
-				}
-				if offset == uint64(len(payload)) {
-					break
-				}
+			messageSet, consumed, err := decodeMessageSet(payload[offset:], int(fetchResponse[i].TopicDatas[j].MessageSetSize))
+			if err != nil {
+				return nil, err
 			}
+			fetchResponse[i].TopicDatas[j].MessageSet = messageSet
+			offset += uint64(consumed)
 		}
 	}
 
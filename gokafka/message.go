@@ -0,0 +1,42 @@
+package gokafka
+
+// MessageHeader is a single key/value pair attached to a v2 (RecordBatch)
+// record. Headers do not exist in the v0/v1 message formats.
+type MessageHeader struct {
+	Key   string
+	Value []byte
+}
+
+// Message is the client-facing view of one record, regardless of which wire
+// format (v0 MessageSet, v1 MessageSet, v2 RecordBatch) it was decoded from.
+type Message struct {
+	Offset      int64
+	MessageSize int32
+	Crc         uint32
+	MagicByte   int8
+	Attributes  int8
+
+	// Timestamp is populated for magic >= 1. It is CreateTime/LogAppendTime
+	// depending on the batch's timestamp type attribute bit.
+	Timestamp int64
+
+	Key   []byte
+	Value []byte
+
+	// Headers is only populated for magic 2 (RecordBatch) messages.
+	Headers []MessageHeader
+}
+
+type MessageSet []Message
+
+// compression codecs selected by the low 3 bits of the v0/v1 Attributes
+// byte or the v2 RecordBatch Attributes field.
+const (
+	CompressionNone   = 0
+	CompressionGzip   = 1
+	CompressionSnappy = 2
+	CompressionLZ4    = 3
+	CompressionZstd   = 4
+
+	compressionCodecMask = 0x07
+)
@@ -0,0 +1,132 @@
+package gokafka
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+)
+
+// magicByteOffset is the byte offset of the MagicByte field relative to the
+// start of one MessageSet entry, for v0, v1 *and* v2: v0/v1 entries start
+// with Offset(8) MessageSize(4) Crc(4) Magic(1); v2 RecordBatch entries
+// start with BaseOffset(8) BatchLength(4) PartitionLeaderEpoch(4) Magic(1).
+// Both layouts happen to put Magic at the same offset, which lets callers
+// decide how to parse an entry before committing to either format.
+const magicByteOffset = 16
+
+// decodeMessageSet decodes a partition's MessageSet/RecordBatch data,
+// dispatching per-entry on MagicByte: 0 and 1 use the legacy MessageSet
+// layout, 2 uses the RecordBatch layout. size is the byte length of the
+// data to decode, as reported by the surrounding FetchResponse.
+func decodeMessageSet(payload []byte, size int) (MessageSet, int, error) {
+	if size > len(payload) {
+		size = len(payload)
+	}
+
+	messages := make(MessageSet, 0)
+	offset := 0
+	for offset < size {
+		if offset+magicByteOffset+1 > size {
+			break
+		}
+		magic := int8(payload[offset+magicByteOffset])
+
+		switch magic {
+		case 0, 1:
+			msg, consumed, err := decodeLegacyMessage(payload[offset:size], magic)
+			if err != nil {
+				return nil, 0, err
+			}
+			messages = append(messages, msg...)
+			offset += consumed
+		case 2:
+			msg, consumed, err := decodeRecordBatch(payload[offset:size])
+			if err != nil {
+				return nil, 0, err
+			}
+			messages = append(messages, msg...)
+			offset += consumed
+		default:
+			return nil, 0, fmt.Errorf("unsupported message magic byte: %d", magic)
+		}
+	}
+
+	return messages, offset, nil
+}
+
+// decodeLegacyMessage decodes one v0 or v1 MessageSet entry (Offset,
+// MessageSize, and then the Message itself), including the wrapper message
+// used to carry a compressed inner MessageSet.
+func decodeLegacyMessage(payload []byte, magic int8) (MessageSet, int, error) {
+	offset := 0
+	msgOffset := int64(binary.BigEndian.Uint64(payload[offset:]))
+	offset += 8
+	messageSize := int32(binary.BigEndian.Uint32(payload[offset:]))
+	offset += 4
+	messageEnd := offset + int(messageSize)
+	if messageEnd > len(payload) {
+		return nil, 0, fmt.Errorf("message size %d exceeds available bytes %d", messageSize, len(payload)-offset)
+	}
+
+	crc := binary.BigEndian.Uint32(payload[offset:])
+	offset += 4
+	crcDataStart := offset // Magic is the first byte the CRC covers
+	gotMagic := int8(payload[offset])
+	offset += 1
+	attributes := int8(payload[offset])
+	offset += 1
+
+	var timestamp int64
+	if magic >= 1 {
+		timestamp = int64(binary.BigEndian.Uint64(payload[offset:]))
+		offset += 8
+	}
+
+	keyLength := int32(binary.BigEndian.Uint32(payload[offset:]))
+	offset += 4
+	var key []byte
+	if keyLength != -1 {
+		key = make([]byte, keyLength)
+		copy(key, payload[offset:offset+int(keyLength)])
+		offset += int(keyLength)
+	}
+
+	valueLength := int32(binary.BigEndian.Uint32(payload[offset:]))
+	offset += 4
+	var value []byte
+	if valueLength != -1 {
+		value = make([]byte, valueLength)
+		copy(value, payload[offset:offset+int(valueLength)])
+		offset += int(valueLength)
+	}
+
+	if got := crc32.ChecksumIEEE(payload[crcDataStart:messageEnd]); got != crc {
+		return nil, 0, fmt.Errorf("message crc mismatch: expected %d, got %d", crc, got)
+	}
+
+	codec := attributes & compressionCodecMask
+	if codec != 0 {
+		inner, err := decompress(codec, value)
+		if err != nil {
+			return nil, 0, err
+		}
+		innerSet, _, err := decodeMessageSet(inner, len(inner))
+		if err != nil {
+			return nil, 0, err
+		}
+		return innerSet, messageEnd, nil
+	}
+
+	msg := Message{
+		Offset:      msgOffset,
+		MessageSize: messageSize,
+		Crc:         crc,
+		MagicByte:   gotMagic,
+		Attributes:  attributes,
+		Timestamp:   timestamp,
+		Key:         key,
+		Value:       value,
+	}
+
+	return MessageSet{msg}, messageEnd, nil
+}
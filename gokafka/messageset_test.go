@@ -0,0 +1,280 @@
+package gokafka
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"hash/crc32"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+)
+
+// encodeLegacyMessageForTest builds a single v0/v1 MessageSet entry
+// (Offset, MessageSize, Message), the way a real broker would, so the
+// decode path can be exercised without a server.
+func encodeLegacyMessageForTest(magic int8, offset int64, timestamp int64, key, value []byte) []byte {
+	msg := []byte{byte(magic), 0} // Magic, Attributes
+	if magic >= 1 {
+		ts := make([]byte, 8)
+		binary.BigEndian.PutUint64(ts, uint64(timestamp))
+		msg = append(msg, ts...)
+	}
+	msg = append(msg, encodeLegacyBytesForTest(key)...)
+	msg = append(msg, encodeLegacyBytesForTest(value)...)
+
+	crc := crc32.ChecksumIEEE(msg)
+
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, uint64(offset))
+	sizeBuf := make([]byte, 4)
+	binary.BigEndian.PutUint32(sizeBuf, uint32(4+len(msg)))
+	buf = append(buf, sizeBuf...)
+	crcBuf := make([]byte, 4)
+	binary.BigEndian.PutUint32(crcBuf, crc)
+	buf = append(buf, crcBuf...)
+	return append(buf, msg...)
+}
+
+func encodeLegacyBytesForTest(data []byte) []byte {
+	l := make([]byte, 4)
+	if data == nil {
+		binary.BigEndian.PutUint32(l, ^uint32(0)) // -1: null bytes field
+		return l
+	}
+	binary.BigEndian.PutUint32(l, uint32(len(data)))
+	return append(l, data...)
+}
+
+func TestDecodeMessageSetV0(t *testing.T) {
+	payload := encodeLegacyMessageForTest(0, 0, 0, []byte("k"), []byte("v0 message"))
+
+	messages, consumed, err := decodeMessageSet(payload, len(payload))
+	if err != nil {
+		t.Fatalf("decodeMessageSet: %v", err)
+	}
+	if consumed != len(payload) {
+		t.Fatalf("consumed = %d, want %d", consumed, len(payload))
+	}
+	if len(messages) != 1 || messages[0].MagicByte != 0 || string(messages[0].Value) != "v0 message" {
+		t.Fatalf("unexpected messages: %+v", messages)
+	}
+}
+
+func TestDecodeMessageSetV1(t *testing.T) {
+	payload := encodeLegacyMessageForTest(1, 0, 1700000000000, []byte("k"), []byte("v1 message"))
+
+	messages, _, err := decodeMessageSet(payload, len(payload))
+	if err != nil {
+		t.Fatalf("decodeMessageSet: %v", err)
+	}
+	if len(messages) != 1 || messages[0].MagicByte != 1 || messages[0].Timestamp != 1700000000000 {
+		t.Fatalf("unexpected messages: %+v", messages)
+	}
+}
+
+func TestDecodeLegacyMessageCRCMismatch(t *testing.T) {
+	payload := encodeLegacyMessageForTest(0, 0, 0, []byte("k"), []byte("v"))
+	payload[len(payload)-1] ^= 0xff // corrupt the value without touching its length
+
+	if _, _, err := decodeMessageSet(payload, len(payload)); err == nil {
+		t.Fatal("expected a crc mismatch error, got nil")
+	}
+}
+
+func TestDecodeMessageSetV2RoundTrip(t *testing.T) {
+	records := []Record{
+		{Key: []byte("k1"), Value: []byte("v1"), Headers: []MessageHeader{{Key: "h", Value: []byte("hv")}}},
+		{Key: nil, Value: []byte("v2")},
+	}
+	payload := EncodeRecordBatch(records, 0, 1700000000000)
+
+	messages, consumed, err := decodeMessageSet(payload, len(payload))
+	if err != nil {
+		t.Fatalf("decodeMessageSet: %v", err)
+	}
+	if consumed != len(payload) {
+		t.Fatalf("consumed = %d, want %d", consumed, len(payload))
+	}
+	if len(messages) != 2 {
+		t.Fatalf("got %d messages, want 2", len(messages))
+	}
+	if string(messages[0].Key) != "k1" || string(messages[0].Value) != "v1" {
+		t.Fatalf("unexpected messages[0]: %+v", messages[0])
+	}
+	if len(messages[0].Headers) != 1 || messages[0].Headers[0].Key != "h" || string(messages[0].Headers[0].Value) != "hv" {
+		t.Fatalf("unexpected headers: %+v", messages[0].Headers)
+	}
+	if messages[1].Key != nil || string(messages[1].Value) != "v2" {
+		t.Fatalf("unexpected messages[1]: %+v", messages[1])
+	}
+	if messages[0].Timestamp != 1700000000000 || messages[1].Timestamp != 1700000000000 {
+		t.Fatalf("unexpected timestamps: %d, %d", messages[0].Timestamp, messages[1].Timestamp)
+	}
+}
+
+// gzipCompressForTest, xerialSnappyCompressForTest, lz4CompressForTest and
+// zstdCompressForTest each compress raw into the wire format the real codec
+// produces, so buildCompressedRecordBatchForTest can exercise decompress's
+// handling of every codec decodeMessageSet supports.
+func gzipCompressForTest(t *testing.T, raw []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	if _, err := gw.Write(raw); err != nil {
+		t.Fatalf("gzip write: %v", err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatalf("gzip close: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// xerialSnappyCompressForTest wraps raw in the xerial framing real Kafka
+// snappy payloads use: magic, version, compatible version, then a single
+// length-prefixed snappy-compressed block.
+func xerialSnappyCompressForTest(t *testing.T, raw []byte) []byte {
+	t.Helper()
+	block := snappy.Encode(nil, raw)
+
+	out := append([]byte(nil), xerialSnappyMagic...)
+	versionBuf := make([]byte, 8)
+	binary.BigEndian.PutUint32(versionBuf[0:], 1)
+	binary.BigEndian.PutUint32(versionBuf[4:], 1)
+	out = append(out, versionBuf...)
+
+	lenBuf := make([]byte, 4)
+	binary.BigEndian.PutUint32(lenBuf, uint32(len(block)))
+	out = append(out, lenBuf...)
+	return append(out, block...)
+}
+
+func lz4CompressForTest(t *testing.T, raw []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := lz4.NewWriter(&buf)
+	if _, err := zw.Write(raw); err != nil {
+		t.Fatalf("lz4 write: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("lz4 close: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func zstdCompressForTest(t *testing.T, raw []byte) []byte {
+	t.Helper()
+	enc, err := zstd.NewWriter(nil)
+	if err != nil {
+		t.Fatalf("zstd.NewWriter: %v", err)
+	}
+	defer enc.Close()
+	return enc.EncodeAll(raw, nil)
+}
+
+// buildCompressedRecordBatchForTest mirrors EncodeRecordBatch but compresses
+// the records section with compress and sets the compression attribute
+// bits, since EncodeRecordBatch itself never produces a compressed batch.
+func buildCompressedRecordBatchForTest(t *testing.T, records []Record, codec int8, firstTimestamp int64, compress func(t *testing.T, raw []byte) []byte) []byte {
+	t.Helper()
+
+	var rawRecords []byte
+	for i, r := range records {
+		rawRecords = append(rawRecords, encodeRecord(r, int64(i), 0)...)
+	}
+
+	recordsData := compress(t, rawRecords)
+
+	lastOffsetDelta := int32(len(records) - 1)
+
+	body := make([]byte, 0, recordBatchHeaderSize-12+len(recordsData))
+	body = append(body, make([]byte, 4)...) // PartitionLeaderEpoch
+	body = append(body, 2)                  // Magic
+	crcFieldOffset := len(body)
+	body = append(body, make([]byte, 4)...) // CRC, filled in below
+
+	attrBuf := make([]byte, 2)
+	binary.BigEndian.PutUint16(attrBuf, uint16(codec))
+	body = append(body, attrBuf...)
+
+	lastOffsetDeltaBuf := make([]byte, 4)
+	binary.BigEndian.PutUint32(lastOffsetDeltaBuf, uint32(lastOffsetDelta))
+	body = append(body, lastOffsetDeltaBuf...)
+
+	tsBuf := make([]byte, 8)
+	binary.BigEndian.PutUint64(tsBuf, uint64(firstTimestamp))
+	body = append(body, tsBuf...) // FirstTimestamp
+	body = append(body, tsBuf...) // MaxTimestamp
+
+	body = append(body, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff) // ProducerId: -1
+	body = append(body, 0xff, 0xff)                                     // ProducerEpoch: -1
+	body = append(body, 0xff, 0xff, 0xff, 0xff)                         // BaseSequence: -1
+
+	countBuf := make([]byte, 4)
+	binary.BigEndian.PutUint32(countBuf, uint32(len(records)))
+	body = append(body, countBuf...)
+
+	body = append(body, recordsData...)
+
+	crc := crc32.Checksum(body[crcFieldOffset+4:], castagnoliTable)
+	binary.BigEndian.PutUint32(body[crcFieldOffset:], crc)
+
+	out := make([]byte, 0, 12+len(body))
+	out = append(out, make([]byte, 8)...) // BaseOffset: 0
+	batchLenBuf := make([]byte, 4)
+	binary.BigEndian.PutUint32(batchLenBuf, uint32(len(body)))
+	out = append(out, batchLenBuf...)
+	return append(out, body...)
+}
+
+func TestDecodeMessageSetV2Compressed(t *testing.T) {
+	tests := []struct {
+		name     string
+		codec    int8
+		compress func(t *testing.T, raw []byte) []byte
+	}{
+		{"gzip", CompressionGzip, gzipCompressForTest},
+		{"snappy", CompressionSnappy, xerialSnappyCompressForTest},
+		{"lz4", CompressionLZ4, lz4CompressForTest},
+		{"zstd", CompressionZstd, zstdCompressForTest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records := []Record{
+				{Key: []byte("k"), Value: []byte("compressed value 1")},
+				{Key: []byte("k2"), Value: []byte("compressed value 2")},
+			}
+			payload := buildCompressedRecordBatchForTest(t, records, tt.codec, 1700000000000, tt.compress)
+
+			messages, consumed, err := decodeMessageSet(payload, len(payload))
+			if err != nil {
+				t.Fatalf("decodeMessageSet: %v", err)
+			}
+			if consumed != len(payload) {
+				t.Fatalf("consumed = %d, want %d", consumed, len(payload))
+			}
+			if len(messages) != 2 || string(messages[0].Value) != "compressed value 1" || string(messages[1].Value) != "compressed value 2" {
+				t.Fatalf("unexpected messages: %+v", messages)
+			}
+		})
+	}
+}
+
+// TestDecodeXerialSnappyRawFallback verifies decompress still handles a
+// non-xerial-framed snappy payload (a single raw block), the shape a
+// non-standard producer might still emit.
+func TestDecodeXerialSnappyRawFallback(t *testing.T) {
+	raw := []byte("a single raw snappy block, no xerial framing")
+	compressed := snappy.Encode(nil, raw)
+
+	got, err := decompress(CompressionSnappy, compressed)
+	if err != nil {
+		t.Fatalf("decompress: %v", err)
+	}
+	if string(got) != string(raw) {
+		t.Fatalf("decompress = %q, want %q", got, raw)
+	}
+}
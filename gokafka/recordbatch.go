@@ -0,0 +1,245 @@
+package gokafka
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+)
+
+var castagnoliTable = crc32.MakeTable(crc32.Castagnoli)
+
+// recordBatchHeaderSize is the number of bytes from BaseOffset up to and
+// including BaseSequence/RecordsCount, i.e. everything before the Records
+// array itself.
+const recordBatchHeaderSize = 8 + 4 + 4 + 1 + 4 + 2 + 4 + 8 + 8 + 8 + 2 + 4 + 4
+
+// decodeRecordBatch decodes a single v2 RecordBatch starting at payload[0]
+// (the BaseOffset field) and returns the records it contains plus the total
+// number of bytes consumed (BaseOffset+BatchLength fields included).
+func decodeRecordBatch(payload []byte) (MessageSet, int, error) {
+	if len(payload) < recordBatchHeaderSize {
+		return nil, 0, fmt.Errorf("record batch shorter than header: %d bytes", len(payload))
+	}
+
+	offset := 0
+	baseOffset := int64(binary.BigEndian.Uint64(payload[offset:]))
+	offset += 8
+	batchLength := int32(binary.BigEndian.Uint32(payload[offset:]))
+	offset += 4
+	totalLength := 12 + int(batchLength)
+	if totalLength > len(payload) {
+		return nil, 0, fmt.Errorf("record batch length %d exceeds available bytes %d", totalLength, len(payload))
+	}
+
+	offset += 4 // PartitionLeaderEpoch
+	offset += 1 // Magic, already dispatched on by the caller
+
+	crc := int32(binary.BigEndian.Uint32(payload[offset:]))
+	crcOffset := offset + 4
+	if got := int32(crc32.Checksum(payload[crcOffset:totalLength], castagnoliTable)); got != crc {
+		return nil, 0, fmt.Errorf("record batch crc mismatch: expected %d, got %d", crc, got)
+	}
+	offset += 4
+
+	attributes := int16(binary.BigEndian.Uint16(payload[offset:]))
+	offset += 2
+	offset += 4 // LastOffsetDelta
+	firstTimestamp := int64(binary.BigEndian.Uint64(payload[offset:]))
+	offset += 8
+	offset += 8 // MaxTimestamp
+	offset += 8 // ProducerId
+	offset += 2 // ProducerEpoch
+	offset += 4 // BaseSequence
+
+	recordsCount := int32(binary.BigEndian.Uint32(payload[offset:]))
+	offset += 4
+
+	recordsData := payload[offset:totalLength]
+	codec := int8(attributes) & compressionCodecMask
+	if codec != 0 {
+		decompressed, err := decompress(codec, recordsData)
+		if err != nil {
+			return nil, 0, err
+		}
+		recordsData = decompressed
+	}
+
+	messages := make(MessageSet, 0, recordsCount)
+	pos := 0
+	for i := int32(0); i < recordsCount && pos < len(recordsData); i++ {
+		msg, n, err := decodeRecord(recordsData[pos:], baseOffset, firstTimestamp)
+		if err != nil {
+			return nil, 0, err
+		}
+		messages = append(messages, msg)
+		pos += n
+	}
+
+	return messages, totalLength, nil
+}
+
+// decodeRecord decodes a single Record from a (possibly decompressed)
+// RecordBatch records buffer and reconstructs absolute offset/timestamp.
+func decodeRecord(data []byte, baseOffset, firstTimestamp int64) (Message, int, error) {
+	length, n := decodeVarint(data)
+	if n+int(length) > len(data) {
+		return Message{}, 0, fmt.Errorf("record length %d exceeds available bytes %d", length, len(data)-n)
+	}
+	record := data[n : n+int(length)]
+	consumed := n + int(length)
+
+	pos := 0
+	attributes := int8(record[pos])
+	pos++
+
+	timestampDelta, n := decodeVarint(record[pos:])
+	pos += n
+	offsetDelta, n := decodeVarint(record[pos:])
+	pos += n
+
+	msg := Message{
+		MagicByte:  2,
+		Attributes: attributes,
+		Offset:     baseOffset + offsetDelta,
+		Timestamp:  firstTimestamp + timestampDelta,
+	}
+
+	keyLen, n := decodeVarint(record[pos:])
+	pos += n
+	if keyLen >= 0 {
+		msg.Key = append([]byte(nil), record[pos:pos+int(keyLen)]...)
+		pos += int(keyLen)
+	}
+
+	valueLen, n := decodeVarint(record[pos:])
+	pos += n
+	if valueLen >= 0 {
+		msg.Value = append([]byte(nil), record[pos:pos+int(valueLen)]...)
+		pos += int(valueLen)
+	}
+
+	headerCount, n := decodeVarint(record[pos:])
+	pos += n
+	if headerCount > 0 {
+		msg.Headers = make([]MessageHeader, headerCount)
+		for i := int64(0); i < headerCount; i++ {
+			keyLen, n := decodeVarint(record[pos:])
+			pos += n
+			key := string(record[pos : pos+int(keyLen)])
+			pos += int(keyLen)
+
+			valLen, n := decodeVarint(record[pos:])
+			pos += n
+			var val []byte
+			if valLen >= 0 {
+				val = append([]byte(nil), record[pos:pos+int(valLen)]...)
+				pos += int(valLen)
+			}
+
+			msg.Headers[i] = MessageHeader{Key: key, Value: val}
+		}
+	}
+
+	return msg, consumed, nil
+}
+
+// Record is the producer-facing counterpart of Message: the fields a caller
+// controls when building a batch to send, as opposed to the ones the broker
+// or the wire format assigns (Offset, Crc, Timestamp deltas, ...).
+type Record struct {
+	Key     []byte
+	Value   []byte
+	Headers []MessageHeader
+}
+
+// EncodeRecordBatch serializes records as a single uncompressed v2
+// RecordBatch, the inverse of decodeRecordBatch. baseOffset is almost
+// always 0 for a batch a producer is about to send (the broker assigns the
+// real offsets in its response); firstTimestampMS is the batch's CreateTime
+// in epoch milliseconds.
+func EncodeRecordBatch(records []Record, baseOffset int64, firstTimestampMS int64) []byte {
+	recordsData := make([]byte, 0)
+	for i, r := range records {
+		recordsData = append(recordsData, encodeRecord(r, int64(i), 0)...)
+	}
+
+	lastOffsetDelta := int32(0)
+	if n := len(records); n > 0 {
+		lastOffsetDelta = int32(n - 1)
+	}
+
+	body := make([]byte, 0, recordBatchHeaderSize-12+len(recordsData))
+	body = append(body, make([]byte, 4)...) // PartitionLeaderEpoch
+	body = append(body, 2)                  // Magic
+	crcFieldOffset := len(body)
+	body = append(body, make([]byte, 4)...) // CRC, filled in below
+
+	body = append(body, make([]byte, 2)...) // Attributes: no compression, CreateTime
+
+	lastOffsetDeltaBuf := make([]byte, 4)
+	binary.BigEndian.PutUint32(lastOffsetDeltaBuf, uint32(lastOffsetDelta))
+	body = append(body, lastOffsetDeltaBuf...)
+
+	timestampBuf := make([]byte, 8)
+	binary.BigEndian.PutUint64(timestampBuf, uint64(firstTimestampMS))
+	body = append(body, timestampBuf...) // FirstTimestamp
+	body = append(body, timestampBuf...) // MaxTimestamp: no per-record timestamp deltas yet
+
+	body = append(body, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff) // ProducerId: -1, not an idempotent/transactional producer
+	body = append(body, 0xff, 0xff)                                     // ProducerEpoch: -1
+	body = append(body, 0xff, 0xff, 0xff, 0xff)                         // BaseSequence: -1
+
+	countBuf := make([]byte, 4)
+	binary.BigEndian.PutUint32(countBuf, uint32(len(records)))
+	body = append(body, countBuf...)
+
+	body = append(body, recordsData...)
+
+	crc := crc32.Checksum(body[crcFieldOffset+4:], castagnoliTable)
+	binary.BigEndian.PutUint32(body[crcFieldOffset:], crc)
+
+	out := make([]byte, 0, 12+len(body))
+	baseOffsetBuf := make([]byte, 8)
+	binary.BigEndian.PutUint64(baseOffsetBuf, uint64(baseOffset))
+	out = append(out, baseOffsetBuf...)
+
+	batchLengthBuf := make([]byte, 4)
+	binary.BigEndian.PutUint32(batchLengthBuf, uint32(len(body)))
+	out = append(out, batchLengthBuf...)
+
+	return append(out, body...)
+}
+
+// encodeRecord serializes one Record as a v2 Record, the inverse of
+// decodeRecord: a varint-length-prefixed Attributes, TimestampDelta,
+// OffsetDelta, Key, Value, Headers.
+func encodeRecord(r Record, offsetDelta, timestampDelta int64) []byte {
+	body := make([]byte, 0, 16+len(r.Key)+len(r.Value))
+	body = append(body, 0) // Attributes
+
+	body = append(body, encodeVarint(timestampDelta)...)
+	body = append(body, encodeVarint(offsetDelta)...)
+	body = append(body, encodeVarintBytes(r.Key)...)
+	body = append(body, encodeVarintBytes(r.Value)...)
+
+	body = append(body, encodeVarint(int64(len(r.Headers)))...)
+	for _, h := range r.Headers {
+		body = append(body, encodeVarint(int64(len(h.Key)))...)
+		body = append(body, h.Key...)
+		body = append(body, encodeVarintBytes(h.Value)...)
+	}
+
+	out := make([]byte, 0, len(body)+5)
+	out = append(out, encodeVarint(int64(len(body)))...)
+	return append(out, body...)
+}
+
+// encodeVarintBytes writes data's varint length followed by data itself, or
+// a length of -1 and nothing else when data is nil (the v2 wire encoding of
+// a null key/value/header value).
+func encodeVarintBytes(data []byte) []byte {
+	if data == nil {
+		return encodeVarint(-1)
+	}
+	return append(encodeVarint(int64(len(data))), data...)
+}
@@ -0,0 +1,45 @@
+package gokafka
+
+// decodeVarint reads a base-128 varint from the front of payload and
+// returns the decoded (zig-zag) value along with the number of bytes
+// consumed. It is used by the v2 RecordBatch format, where Length,
+// TimestampDelta, OffsetDelta, KeyLength, ValueLength and the header
+// lengths are all encoded this way.
+func decodeVarint(payload []byte) (int64, int) {
+	var rawValue uint64
+	var shift uint
+	var n int
+
+	for n = 0; n < len(payload); n++ {
+		b := payload[n]
+		rawValue |= uint64(b&0x7f) << shift
+		if b&0x80 == 0 {
+			n++
+			break
+		}
+		shift += 7
+	}
+
+	value := int64(rawValue>>1) ^ -(int64(rawValue) & 1)
+	return value, n
+}
+
+// encodeVarint zig-zag/base-128 varint encodes value, the inverse of
+// decodeVarint. Used by EncodeRecordBatch to write Length, TimestampDelta,
+// OffsetDelta, KeyLength, ValueLength and the header lengths.
+func encodeVarint(value int64) []byte {
+	zigzag := uint64((value << 1) ^ (value >> 63))
+
+	buf := make([]byte, 0, 10)
+	for {
+		b := byte(zigzag & 0x7f)
+		zigzag >>= 7
+		if zigzag != 0 {
+			buf = append(buf, b|0x80)
+		} else {
+			buf = append(buf, b)
+			break
+		}
+	}
+	return buf
+}
@@ -0,0 +1,415 @@
+package healer
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/sundy-li/healer/gokafka"
+)
+
+// ProducerMessage is a single record submitted to an AsyncProducer. Metadata
+// is not inspected by AsyncProducer itself; Producer uses it to correlate
+// acks back to the caller.
+type ProducerMessage struct {
+	Topic     string
+	Partition int32
+	Key       []byte
+	Value     []byte
+	Headers   []gokafka.MessageHeader
+	Metadata  interface{}
+
+	retries int
+}
+
+// ProducerError pairs a message that could not be delivered with the error
+// that caused AsyncProducer to give up on it.
+type ProducerError struct {
+	Msg *ProducerMessage
+	Err error
+}
+
+func (pe *ProducerError) Error() string {
+	return pe.Err.Error()
+}
+
+var errAsyncProducerClosed = errors.New("async producer is closed")
+
+// AsyncProducer dispatches messages to one goroutine per (topic, partition),
+// batches them, and sends each batch through a circuit breaker with retry,
+// following the design of Sarama's async producer. Leader lookups and
+// metadata refresh are delegated to a shared *Client (see client.go) rather
+// than each producer redialing bootstrap servers on its own.
+type AsyncProducer struct {
+	config *ProducerConfig
+	client *Client
+
+	input     chan *ProducerMessage
+	successes chan *ProducerMessage
+	errors    chan *ProducerError
+
+	mu         sync.Mutex
+	partitions map[string]map[int32]*partitionProducer
+	wg         sync.WaitGroup
+
+	closing chan struct{}
+	closed  chan struct{}
+}
+
+// NewAsyncProducer builds an AsyncProducer that shares client's broker pool
+// and metadata cache for leader lookups and refresh.
+func NewAsyncProducer(config *ProducerConfig, client *Client) (*AsyncProducer, error) {
+	if err := config.checkValid(); err != nil {
+		return nil, err
+	}
+
+	p := &AsyncProducer{
+		config:     config,
+		client:     client,
+		input:      make(chan *ProducerMessage),
+		successes:  make(chan *ProducerMessage),
+		errors:     make(chan *ProducerError),
+		partitions: make(map[string]map[int32]*partitionProducer),
+		closing:    make(chan struct{}),
+		closed:     make(chan struct{}),
+	}
+
+	go p.dispatch()
+	return p, nil
+}
+
+func (p *AsyncProducer) Input() chan<- *ProducerMessage     { return p.input }
+func (p *AsyncProducer) Successes() <-chan *ProducerMessage { return p.successes }
+func (p *AsyncProducer) Errors() <-chan *ProducerError      { return p.errors }
+
+// dispatch routes every submitted message to its (topic, partition)
+// goroutine, creating one lazily on first use.
+func (p *AsyncProducer) dispatch() {
+	defer close(p.closed)
+	for {
+		select {
+		case msg, ok := <-p.input:
+			if !ok {
+				return
+			}
+			p.partitionProducerFor(msg.Topic, msg.Partition).input <- msg
+		case <-p.closing:
+			return
+		}
+	}
+}
+
+func (p *AsyncProducer) partitionProducerFor(topic string, partition int32) *partitionProducer {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	byPartition, ok := p.partitions[topic]
+	if !ok {
+		byPartition = make(map[int32]*partitionProducer)
+		p.partitions[topic] = byPartition
+	}
+
+	pp, ok := byPartition[partition]
+	if !ok {
+		pp = newPartitionProducer(p, topic, partition)
+		byPartition[partition] = pp
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			pp.run()
+		}()
+	}
+	return pp
+}
+
+// AsyncClose triggers a shutdown without waiting for in-flight batches to
+// drain; Successes()/Errors() keep delivering until every partition
+// goroutine exits.
+func (p *AsyncProducer) AsyncClose() {
+	close(p.input)
+}
+
+// Close triggers a shutdown and blocks until every in-flight batch has been
+// flushed and every partition goroutine has exited.
+func (p *AsyncProducer) Close() error {
+	p.AsyncClose()
+	<-p.closed
+	p.wg.Wait()
+	close(p.successes)
+	close(p.errors)
+	return nil
+}
+
+// partitionProducer owns delivery for a single (topic, partition): it
+// batches incoming messages and sends each batch through a breaker with
+// retry.
+type partitionProducer struct {
+	parent    *AsyncProducer
+	topic     string
+	partition int32
+	input     chan *ProducerMessage
+	breaker   *Breaker
+}
+
+func newPartitionProducer(parent *AsyncProducer, topic string, partition int32) *partitionProducer {
+	return &partitionProducer{
+		parent:    parent,
+		topic:     topic,
+		partition: partition,
+		input:     make(chan *ProducerMessage, parent.config.MessageMaxCount),
+		breaker:   NewBreaker(5, 30*time.Second),
+	}
+}
+
+func (pp *partitionProducer) run() {
+	flushInterval := time.Duration(pp.parent.config.FlushIntervalMS) * time.Millisecond
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	var batch []*ProducerMessage
+	batchBytes := 0
+
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		pp.send(batch)
+		batch = nil
+		batchBytes = 0
+	}
+
+	for {
+		select {
+		case msg, ok := <-pp.input:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, msg)
+			batchBytes += len(msg.Key) + len(msg.Value)
+			if len(batch) >= pp.parent.config.MessageMaxCount || batchBytes >= pp.parent.config.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// send delivers batch, retrying retriable errors up to config.Retries times
+// with exponential backoff and a forced metadata refresh between attempts.
+func (pp *partitionProducer) send(batch []*ProducerMessage) {
+	pending := batch
+	backoff := 100 * time.Millisecond
+
+	for attempt := 0; ; attempt++ {
+		broker, err := pp.parent.client.Leader(pp.topic, pp.partition)
+		if err == nil {
+			err = pp.breaker.Run(func() error {
+				return pp.deliver(broker, pending)
+			})
+		}
+
+		if err == nil {
+			for _, msg := range pending {
+				pp.parent.successes <- msg
+			}
+			return
+		}
+
+		if attempt >= pp.parent.config.Retries || !isRetriableError(err) {
+			for _, msg := range pending {
+				pp.parent.errors <- &ProducerError{Msg: msg, Err: err}
+			}
+			return
+		}
+
+		pp.parent.client.RefreshMetadata(pp.topic)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// deliver encodes batch as a v2 RecordBatch and issues the Produce request
+// against broker; this is the integration seam the dispatcher above retries
+// and breaker-protects.
+func (pp *partitionProducer) deliver(broker *Broker, batch []*ProducerMessage) error {
+	ctx, span := globalTracer.StartSpan(context.Background(), "kafka.produce.batch")
+	defer span.Finish()
+
+	records := make([]gokafka.Record, len(batch))
+	for i, msg := range batch {
+		headers := headerMapFromMessage(msg)
+		globalTracer.Inject(ctx, headers)
+		msg.Headers = headersFromMap(msg.Headers, headers)
+		records[i] = gokafka.Record{Key: msg.Key, Value: msg.Value, Headers: msg.Headers}
+	}
+
+	recordBatch := gokafka.EncodeRecordBatch(records, 0, time.Now().UnixNano()/int64(time.Millisecond))
+	body := encodeProduceRequest(pp.parent.config.Acks, pp.parent.config.RequestTimeoutMS, pp.topic, pp.partition, recordBatch)
+
+	resp, err := broker.RequestWithContext(ctx, "Produce", apiKeyProduce, produceAPIVersion, body, pp.topic, pp.partition)
+	if err != nil {
+		return err
+	}
+	return decodeProduceResponse(resp)
+}
+
+// produceAPIVersion is the lowest Produce version whose response shape this
+// client decodes and whose request records it encodes as v2 RecordBatches.
+const produceAPIVersion = 7
+
+// encodeProduceRequest builds a Produce request body for a single-topic,
+// single-partition batch already encoded as a RecordBatch.
+func encodeProduceRequest(acks int16, timeoutMS int32, topic string, partition int32, recordBatch []byte) []byte {
+	buf := make([]byte, 2)
+	binary.BigEndian.PutUint16(buf, uint16(acks))
+
+	timeoutBuf := make([]byte, 4)
+	binary.BigEndian.PutUint32(timeoutBuf, uint32(timeoutMS))
+	buf = append(buf, timeoutBuf...)
+
+	topicsCount := make([]byte, 4)
+	binary.BigEndian.PutUint32(topicsCount, 1)
+	buf = append(buf, topicsCount...)
+
+	topicLen := make([]byte, 2)
+	binary.BigEndian.PutUint16(topicLen, uint16(len(topic)))
+	buf = append(buf, topicLen...)
+	buf = append(buf, topic...)
+
+	partitionsCount := make([]byte, 4)
+	binary.BigEndian.PutUint32(partitionsCount, 1)
+	buf = append(buf, partitionsCount...)
+
+	partitionBuf := make([]byte, 4)
+	binary.BigEndian.PutUint32(partitionBuf, uint32(partition))
+	buf = append(buf, partitionBuf...)
+
+	recordsLen := make([]byte, 4)
+	binary.BigEndian.PutUint32(recordsLen, uint32(len(recordBatch)))
+	buf = append(buf, recordsLen...)
+	buf = append(buf, recordBatch...)
+
+	return buf
+}
+
+// decodeProduceResponse reads the single (topic, partition) error code out
+// of a Produce response and turns it into the same sentinel errors
+// isRetriableError and the rest of this package's error handling expect.
+func decodeProduceResponse(payload []byte) error {
+	offset := 4 // topics array count, always 1 for the request shape above
+	topicNameLen := int(binary.BigEndian.Uint16(payload[offset:]))
+	offset += 2 + topicNameLen
+	offset += 4 // partitions array count, always 1
+
+	offset += 4 // partition index
+	errorCode := int16(binary.BigEndian.Uint16(payload[offset:]))
+	if errorCode != 0 {
+		return getErrorFromErrorCode(errorCode)
+	}
+	return nil
+}
+
+func headerMapFromMessage(msg *ProducerMessage) map[string][]byte {
+	headers := make(map[string][]byte, len(msg.Headers))
+	for _, h := range msg.Headers {
+		headers[h.Key] = h.Value
+	}
+	return headers
+}
+
+// headersFromMap rebuilds a []gokafka.MessageHeader from headers, which may
+// carry tracer.Inject additions (e.g. a "traceparent" entry) on top of
+// msg.Headers' original keys. Existing keys keep their original order;
+// injected keys are appended after them.
+func headersFromMap(original []gokafka.MessageHeader, headers map[string][]byte) []gokafka.MessageHeader {
+	seen := make(map[string]bool, len(headers))
+	out := make([]gokafka.MessageHeader, 0, len(headers))
+	for _, h := range original {
+		if v, ok := headers[h.Key]; ok {
+			out = append(out, gokafka.MessageHeader{Key: h.Key, Value: v})
+			seen[h.Key] = true
+		}
+	}
+	for k, v := range headers {
+		if !seen[k] {
+			out = append(out, gokafka.MessageHeader{Key: k, Value: v})
+		}
+	}
+	return out
+}
+
+const apiKeyProduce = 0
+
+// isRetriableError reports whether err is one of the transient Kafka errors
+// worth retrying against a freshly refreshed leader, as opposed to a
+// permanent rejection of the request.
+func isRetriableError(err error) bool {
+	switch err {
+	case NotLeaderForPartition, LeaderNotAvailable, NetworkException, RequestTimedOut, ErrBreakerOpen:
+		return true
+	default:
+		return false
+	}
+}
+
+// Producer is the synchronous API: it feeds AsyncProducer and blocks the
+// caller until the message it just sent has been acked or failed.
+type Producer struct {
+	async *AsyncProducer
+}
+
+// NewProducer wraps a synchronous API around a new AsyncProducer.
+func NewProducer(config *ProducerConfig, client *Client) (*Producer, error) {
+	async, err := NewAsyncProducer(config, client)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Producer{async: async}
+	go p.forwardResults()
+	return p, nil
+}
+
+// forwardResults drains Successes()/Errors() and notifies whichever
+// SendMessage call is waiting on that particular message, correlated via
+// the ack channel stashed in ProducerMessage.Metadata.
+func (p *Producer) forwardResults() {
+	successes := p.async.Successes()
+	errs := p.async.Errors()
+	for successes != nil || errs != nil {
+		select {
+		case msg, ok := <-successes:
+			if !ok {
+				successes = nil
+				continue
+			}
+			if ack, ok := msg.Metadata.(chan error); ok {
+				ack <- nil
+			}
+		case pe, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			if ack, ok := pe.Msg.Metadata.(chan error); ok {
+				ack <- pe.Err
+			}
+		}
+	}
+}
+
+// SendMessage submits msg and blocks until it has been acked or failed.
+func (p *Producer) SendMessage(msg *ProducerMessage) error {
+	ack := make(chan error, 1)
+	msg.Metadata = ack
+	p.async.Input() <- msg
+	return <-ack
+}
+
+func (p *Producer) Close() error {
+	return p.async.Close()
+}
@@ -0,0 +1,178 @@
+package healer
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"strconv"
+	"strings"
+)
+
+var (
+	errScramServerFirstMessage = errors.New("malformed scram server-first-message")
+	errScramServerFinalMessage = errors.New("malformed scram server-final-message")
+	errScramSignatureMismatch  = errors.New("scram server signature did not match, server may be impersonated")
+)
+
+func scramHashFor(mechanism string) func() hash.Hash {
+	if mechanism == SaslMechanismScramSha512 {
+		return sha512.New
+	}
+	return sha256.New
+}
+
+// authenticateSaslScram runs the SCRAM-SHA-256/512 client-first /
+// server-first / client-final exchange described in RFC 5802, over
+// SaslAuthenticate frames.
+func (b *Broker) authenticateSaslScram(sasl *SaslConfig) error {
+	newHash := scramHashFor(sasl.Mechanism)
+
+	clientNonce, err := scramNonce()
+	if err != nil {
+		return err
+	}
+
+	clientFirstBare := "n=" + scramEscape(sasl.Username) + ",r=" + clientNonce
+	clientFirst := "n,," + clientFirstBare
+
+	resp, err := b.requestRaw(saslAuthenticateAPIKey, 0, encodeSaslAuthenticateRequest([]byte(clientFirst)))
+	if err != nil {
+		return err
+	}
+	serverFirstRaw, err := decodeSaslAuthenticateResponse(resp)
+	if err != nil {
+		return err
+	}
+	serverFirst := string(serverFirstRaw)
+
+	nonce, salt, iterations, err := parseScramServerFirst(serverFirst)
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(nonce, clientNonce) {
+		return errScramServerFirstMessage
+	}
+
+	saltedPassword := scramHi(newHash, sasl.Password, salt, iterations)
+	clientKey := scramHMAC(newHash, saltedPassword, "Client Key")
+	storedKey := scramHash(newHash, clientKey)
+
+	clientFinalWithoutProof := "c=biws,r=" + nonce
+	authMessage := clientFirstBare + "," + serverFirst + "," + clientFinalWithoutProof
+
+	clientSignature := scramHMAC(newHash, storedKey, authMessage)
+	clientProof := scramXOR(clientKey, clientSignature)
+	clientFinal := clientFinalWithoutProof + ",p=" + base64.StdEncoding.EncodeToString(clientProof)
+
+	resp, err = b.requestRaw(saslAuthenticateAPIKey, 0, encodeSaslAuthenticateRequest([]byte(clientFinal)))
+	if err != nil {
+		return err
+	}
+	serverFinalRaw, err := decodeSaslAuthenticateResponse(resp)
+	if err != nil {
+		return err
+	}
+
+	serverKey := scramHMAC(newHash, saltedPassword, "Server Key")
+	serverSignature := scramHMAC(newHash, serverKey, authMessage)
+	return verifyScramServerFinal(string(serverFinalRaw), serverSignature)
+}
+
+func scramNonce() (string, error) {
+	raw := make([]byte, 24)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base64.RawStdEncoding.EncodeToString(raw), nil
+}
+
+// scramEscape applies the ',' and '=' escaping SCRAM requires in usernames.
+func scramEscape(s string) string {
+	s = strings.ReplaceAll(s, "=", "=3D")
+	s = strings.ReplaceAll(s, ",", "=2C")
+	return s
+}
+
+func parseScramServerFirst(msg string) (nonce string, salt []byte, iterations int, err error) {
+	parts := strings.Split(msg, ",")
+	if len(parts) < 3 {
+		return "", nil, 0, errScramServerFirstMessage
+	}
+	for _, part := range parts {
+		switch {
+		case strings.HasPrefix(part, "r="):
+			nonce = strings.TrimPrefix(part, "r=")
+		case strings.HasPrefix(part, "s="):
+			salt, err = base64.StdEncoding.DecodeString(strings.TrimPrefix(part, "s="))
+			if err != nil {
+				return "", nil, 0, fmt.Errorf("%w: %v", errScramServerFirstMessage, err)
+			}
+		case strings.HasPrefix(part, "i="):
+			iterations, err = strconv.Atoi(strings.TrimPrefix(part, "i="))
+			if err != nil {
+				return "", nil, 0, fmt.Errorf("%w: %v", errScramServerFirstMessage, err)
+			}
+		}
+	}
+	if nonce == "" || salt == nil || iterations <= 0 {
+		return "", nil, 0, errScramServerFirstMessage
+	}
+	return nonce, salt, iterations, nil
+}
+
+func verifyScramServerFinal(msg string, expectedSignature []byte) error {
+	if !strings.HasPrefix(msg, "v=") {
+		return errScramServerFinalMessage
+	}
+	got, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(msg, "v="))
+	if err != nil {
+		return fmt.Errorf("%w: %v", errScramServerFinalMessage, err)
+	}
+	if !hmac.Equal(got, expectedSignature) {
+		return errScramSignatureMismatch
+	}
+	return nil
+}
+
+// scramHi is the RFC 5802 Hi(str, salt, i) function: i iterations of HMAC,
+// folded together with XOR.
+func scramHi(newHash func() hash.Hash, password string, salt []byte, iterations int) []byte {
+	mac := hmac.New(newHash, []byte(password))
+	mac.Write(salt)
+	mac.Write([]byte{0, 0, 0, 1})
+	u := mac.Sum(nil)
+	result := append([]byte(nil), u...)
+
+	for i := 1; i < iterations; i++ {
+		mac := hmac.New(newHash, []byte(password))
+		mac.Write(u)
+		u = mac.Sum(nil)
+		result = scramXOR(result, u)
+	}
+	return result
+}
+
+func scramHMAC(newHash func() hash.Hash, key []byte, data string) []byte {
+	mac := hmac.New(newHash, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func scramHash(newHash func() hash.Hash, data []byte) []byte {
+	h := newHash()
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+func scramXOR(a, b []byte) []byte {
+	out := make([]byte, len(a))
+	for i := range a {
+		out[i] = a[i] ^ b[i]
+	}
+	return out
+}
@@ -0,0 +1,178 @@
+package healer
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+)
+
+// security protocol names, following the same family of names used by
+// mainstream kafka clients (security.protocol)
+const (
+	ProtocolPlaintext     = "PLAINTEXT"
+	ProtocolSSL           = "SSL"
+	ProtocolSaslPlaintext = "SASL_PLAINTEXT"
+	ProtocolSaslSSL       = "SASL_SSL"
+)
+
+// sasl mechanism names (sasl.mechanism)
+const (
+	SaslMechanismPlain       = "PLAIN"
+	SaslMechanismScramSha256 = "SCRAM-SHA-256"
+	SaslMechanismScramSha512 = "SCRAM-SHA-512"
+	SaslMechanismGSSAPI      = "GSSAPI"
+)
+
+// TLSConfig holds the ssl.* family of options.
+type TLSConfig struct {
+	CAFile             string `json:"ssl.ca.location"`
+	CertFile           string `json:"ssl.certificate.location"`
+	KeyFile            string `json:"ssl.key.location"`
+	KeyPassword        string `json:"ssl.key.password"`
+	ServerName         string `json:"ssl.endpoint.identification.server.name"`
+	InsecureSkipVerify bool   `json:"ssl.insecure.skip.verify"`
+}
+
+// SaslConfig holds the sasl.* family of options. Username/Password are used
+// by PLAIN and SCRAM-SHA-256/512, Keytab/Principal are used by GSSAPI.
+type SaslConfig struct {
+	Mechanism string `json:"sasl.mechanism"`
+	Username  string `json:"sasl.username"`
+	Password  string `json:"sasl.password"`
+
+	// GSSAPI (Kerberos)
+	KerberosServiceName string `json:"sasl.kerberos.service.name"`
+	KerberosPrincipal   string `json:"sasl.kerberos.principal"`
+	KerberosKeytabPath  string `json:"sasl.kerberos.keytab"`
+	KerberosRealm       string `json:"sasl.kerberos.realm"`
+
+	// GSSAPIInitSecContext and GSSAPIContinueSecContext drive the actual
+	// Kerberos ticket exchange. The standard library has no Kerberos
+	// client, so this package does not produce GSSAPI tokens itself;
+	// callers that need GSSAPI wire in a real one (e.g. gokrb5) by setting
+	// these, following the same pattern used for the locked/keytab
+	// principal above. GSSAPIInitSecContext returns the first token to
+	// send; GSSAPIContinueSecContext is called with each server challenge
+	// until it reports done.
+	GSSAPIInitSecContext     func() (token []byte, err error)                              `json:"-"`
+	GSSAPIContinueSecContext func(serverToken []byte) (token []byte, done bool, err error) `json:"-"`
+}
+
+// SecurityConfig is the nested security block shared by BrokerConfig,
+// ConsumerConfig and ProducerConfig.
+type SecurityConfig struct {
+	Protocol string      `json:"security.protocol"`
+	TLS      *TLSConfig  `json:"ssl"`
+	Sasl     *SaslConfig `json:"sasl"`
+}
+
+func DefaultSecurityConfig() *SecurityConfig {
+	return &SecurityConfig{
+		Protocol: ProtocolPlaintext,
+	}
+}
+
+var (
+	unknownSecurityProtocol  = errors.New("unknown security.protocol")
+	unknownSaslMechanism     = errors.New("unknown sasl.mechanism")
+	saslRequiresUsername     = errors.New("sasl.username is required for the configured sasl.mechanism")
+	gssapiRequiresPrincipal  = errors.New("sasl.kerberos.principal is required for GSSAPI")
+	gssapiRequiresKeyOrPass  = errors.New("one of sasl.kerberos.keytab or sasl.password is required for GSSAPI")
+	scramWithoutTLSDangerous = errors.New("sasl.mechanism is SCRAM but security.protocol does not enable TLS, credentials would be sent unencrypted")
+)
+
+// checkValid validates the combination of protocol/TLS/SASL options. It is
+// intentionally permissive about PLAINTEXT (nothing to check) and strict
+// about SASL mechanisms that require credentials.
+func (s *SecurityConfig) checkValid() error {
+	if s == nil {
+		return nil
+	}
+
+	switch s.Protocol {
+	case "", ProtocolPlaintext, ProtocolSSL, ProtocolSaslPlaintext, ProtocolSaslSSL:
+	default:
+		return unknownSecurityProtocol
+	}
+
+	if s.Sasl == nil {
+		return nil
+	}
+
+	switch s.Sasl.Mechanism {
+	case SaslMechanismPlain, SaslMechanismScramSha256, SaslMechanismScramSha512:
+		if s.Sasl.Username == "" {
+			return saslRequiresUsername
+		}
+		isScram := s.Sasl.Mechanism == SaslMechanismScramSha256 || s.Sasl.Mechanism == SaslMechanismScramSha512
+		if isScram && s.Protocol != ProtocolSaslSSL {
+			return scramWithoutTLSDangerous
+		}
+	case SaslMechanismGSSAPI:
+		if s.Sasl.KerberosPrincipal == "" {
+			return gssapiRequiresPrincipal
+		}
+		if s.Sasl.GSSAPIInitSecContext == nil && s.Sasl.KerberosKeytabPath == "" && s.Sasl.Password == "" {
+			return gssapiRequiresKeyOrPass
+		}
+	default:
+		return unknownSaslMechanism
+	}
+
+	return nil
+}
+
+// usesTLS reports whether the security protocol requires the underlying
+// net.Conn to be wrapped with TLS before any Kafka request is issued.
+func (s *SecurityConfig) usesTLS() bool {
+	return s != nil && (s.Protocol == ProtocolSSL || s.Protocol == ProtocolSaslSSL)
+}
+
+// usesSasl reports whether a SASL handshake/authenticate exchange must run
+// right after the connection (and optional TLS handshake) is established.
+func (s *SecurityConfig) usesSasl() bool {
+	return s != nil && s.Sasl != nil && (s.Protocol == ProtocolSaslPlaintext || s.Protocol == ProtocolSaslSSL)
+}
+
+// buildTLSConfig turns the ssl.* options into a *tls.Config suitable for
+// tls.Client. It returns nil, nil when TLS is not enabled.
+func (s *SecurityConfig) buildTLSConfig() (*tls.Config, error) {
+	if !s.usesTLS() {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: s.TLS != nil && s.TLS.InsecureSkipVerify,
+	}
+
+	if s.TLS == nil {
+		return tlsConfig, nil
+	}
+
+	if s.TLS.ServerName != "" {
+		tlsConfig.ServerName = s.TLS.ServerName
+	}
+
+	if s.TLS.CAFile != "" {
+		caCert, err := ioutil.ReadFile(s.TLS.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, errors.New("failed to parse ssl.ca.location as PEM")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if s.TLS.CertFile != "" || s.TLS.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(s.TLS.CertFile, s.TLS.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
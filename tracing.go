@@ -0,0 +1,88 @@
+package healer
+
+import "context"
+
+// Span is the minimal unit of work a Tracer records. It mirrors the shape
+// shared by opentracing.Span and the OpenTelemetry trace.Span so that an
+// adapter for either can be implemented with a thin wrapper.
+type Span interface {
+	SetTag(key string, value interface{})
+	LogKV(fields ...interface{})
+	Finish()
+}
+
+// Tracer starts spans and propagates them across process boundaries. The
+// zero-overhead default is NoopTracer; callers that want real tracing
+// install one with SetTracer, typically an adapter around an
+// opentracing.Tracer or an OpenTelemetry trace.Tracer.
+type Tracer interface {
+	// StartSpan starts a new span named operationName, optionally as a
+	// child of a span already present in ctx.
+	StartSpan(ctx context.Context, operationName string) (context.Context, Span)
+
+	// Inject serializes the span found in ctx (if any) into header-style
+	// key/value pairs so it can be carried on a produced message.
+	Inject(ctx context.Context, headers map[string][]byte)
+
+	// Extract reconstructs a span context from header-style key/value
+	// pairs carried on a consumed message.
+	Extract(ctx context.Context, headers map[string][]byte) context.Context
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetTag(key string, value interface{}) {}
+func (noopSpan) LogKV(fields ...interface{})          {}
+func (noopSpan) Finish()                              {}
+
+// NoopTracer is a Tracer that does nothing. It is the default so that
+// existing callers who never call SetTracer see zero overhead.
+type NoopTracer struct{}
+
+func (NoopTracer) StartSpan(ctx context.Context, operationName string) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+func (NoopTracer) Inject(ctx context.Context, headers map[string][]byte)                  {}
+func (NoopTracer) Extract(ctx context.Context, headers map[string][]byte) context.Context { return ctx }
+
+var globalTracer Tracer = NoopTracer{}
+
+// SetTracer installs t as the Tracer used for every subsequent broker RPC,
+// producer send, consumer fetch and metadata refresh.
+func SetTracer(t Tracer) {
+	if t == nil {
+		t = NoopTracer{}
+	}
+	globalTracer = t
+}
+
+// startAPISpan starts a span named "kafka.<apiName>" tagged with the fields
+// every Kafka RPC span carries, per the convention used across Request,
+// producer send and consumer fetch.
+func startAPISpan(ctx context.Context, apiName string, apiKey, apiVersion int16, correlationID uint32, clientID, peerAddress string) (context.Context, Span) {
+	ctx, span := globalTracer.StartSpan(ctx, "kafka."+apiName)
+	span.SetTag("peer.address", peerAddress)
+	span.SetTag("kafka.api_key", apiKey)
+	span.SetTag("kafka.api_version", apiVersion)
+	span.SetTag("kafka.correlation_id", correlationID)
+	span.SetTag("kafka.client_id", clientID)
+	return ctx, span
+}
+
+// finishAPISpan tags span with the topic/partition the RPC was about (when
+// applicable) and records err as a span log before finishing it.
+func finishAPISpan(span Span, topic string, partition int32, err error) {
+	if topic != "" {
+		span.SetTag("messaging.destination", topic)
+		span.SetTag("kafka.partition", partition)
+	}
+	if err != nil {
+		span.LogKV("event", "error", "error.object", err)
+	}
+	span.Finish()
+}
+
+// Adapters for opentracing.Tracer and the OpenTelemetry trace.Tracer live in
+// tracing_opentracing.go and tracing_opentelemetry.go: NewOpenTracingTracer
+// and NewOpenTelemetryTracer each wrap the caller's own tracer/propagator
+// and delegate StartSpan/Inject/Extract to it.
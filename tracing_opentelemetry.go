@@ -0,0 +1,96 @@
+package healer
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// openTelemetryTracer adapts an OpenTelemetry trace.Tracer to Tracer.
+type openTelemetryTracer struct {
+	tracer trace.Tracer
+	prop   propagation.TextMapPropagator
+}
+
+// NewOpenTelemetryTracer wraps tracer so it can be installed with
+// SetTracer. prop controls how span context is carried on message headers;
+// passing nil uses otel.GetTextMapPropagator().
+func NewOpenTelemetryTracer(tracer trace.Tracer, prop propagation.TextMapPropagator) Tracer {
+	if prop == nil {
+		prop = otel.GetTextMapPropagator()
+	}
+	return &openTelemetryTracer{tracer: tracer, prop: prop}
+}
+
+type openTelemetrySpan struct {
+	span trace.Span
+}
+
+func (s *openTelemetrySpan) SetTag(key string, value interface{}) {
+	s.span.SetAttributes(otelAttributeFor(key, value))
+}
+
+func (s *openTelemetrySpan) LogKV(fields ...interface{}) {
+	attrs := make([]attribute.KeyValue, 0, len(fields)/2)
+	for i := 0; i+1 < len(fields); i += 2 {
+		key, _ := fields[i].(string)
+		attrs = append(attrs, otelAttributeFor(key, fields[i+1]))
+	}
+	s.span.AddEvent("log", trace.WithAttributes(attrs...))
+}
+
+func (s *openTelemetrySpan) Finish() { s.span.End() }
+
+func (t *openTelemetryTracer) StartSpan(ctx context.Context, operationName string) (context.Context, Span) {
+	ctx, span := t.tracer.Start(ctx, operationName)
+	return ctx, &openTelemetrySpan{span: span}
+}
+
+func (t *openTelemetryTracer) Inject(ctx context.Context, headers map[string][]byte) {
+	t.prop.Inject(ctx, otelHeaderCarrier(headers))
+}
+
+func (t *openTelemetryTracer) Extract(ctx context.Context, headers map[string][]byte) context.Context {
+	return t.prop.Extract(ctx, otelHeaderCarrier(headers))
+}
+
+// otelHeaderCarrier adapts the map[string][]byte header representation
+// ProducerMessage/consumed messages use to propagation.TextMapCarrier.
+type otelHeaderCarrier map[string][]byte
+
+func (c otelHeaderCarrier) Get(key string) string { return string(c[key]) }
+func (c otelHeaderCarrier) Set(key, val string)   { c[key] = []byte(val) }
+func (c otelHeaderCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func otelAttributeFor(key string, value interface{}) attribute.KeyValue {
+	switch v := value.(type) {
+	case string:
+		return attribute.String(key, v)
+	case bool:
+		return attribute.Bool(key, v)
+	case int:
+		return attribute.Int(key, v)
+	case int16:
+		return attribute.Int(key, int(v))
+	case int32:
+		return attribute.Int64(key, int64(v))
+	case int64:
+		return attribute.Int64(key, v)
+	case uint32:
+		return attribute.Int64(key, int64(v))
+	case error:
+		return attribute.String(key, v.Error())
+	default:
+		return attribute.String(key, fmt.Sprint(v))
+	}
+}
@@ -0,0 +1,64 @@
+package healer
+
+import (
+	"context"
+
+	opentracing "github.com/opentracing/opentracing-go"
+)
+
+// openTracingTracer adapts an opentracing.Tracer to Tracer.
+type openTracingTracer struct {
+	tracer opentracing.Tracer
+}
+
+// NewOpenTracingTracer wraps tracer (e.g. a Jaeger or Zipkin client) so it
+// can be installed with SetTracer.
+func NewOpenTracingTracer(tracer opentracing.Tracer) Tracer {
+	return &openTracingTracer{tracer: tracer}
+}
+
+type openTracingSpan struct {
+	span opentracing.Span
+}
+
+func (s *openTracingSpan) SetTag(key string, value interface{}) { s.span.SetTag(key, value) }
+func (s *openTracingSpan) LogKV(fields ...interface{})          { s.span.LogKV(fields...) }
+func (s *openTracingSpan) Finish()                              { s.span.Finish() }
+
+func (t *openTracingTracer) StartSpan(ctx context.Context, operationName string) (context.Context, Span) {
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, t.tracer, operationName)
+	return ctx, &openTracingSpan{span: span}
+}
+
+func (t *openTracingTracer) Inject(ctx context.Context, headers map[string][]byte) {
+	span := opentracing.SpanFromContext(ctx)
+	if span == nil {
+		return
+	}
+	t.tracer.Inject(span.Context(), opentracing.TextMap, openTracingHeaderCarrier(headers))
+}
+
+func (t *openTracingTracer) Extract(ctx context.Context, headers map[string][]byte) context.Context {
+	spanCtx, err := t.tracer.Extract(opentracing.TextMap, openTracingHeaderCarrier(headers))
+	if err != nil {
+		return ctx
+	}
+	span := t.tracer.StartSpan("kafka.consume", opentracing.ChildOf(spanCtx))
+	return opentracing.ContextWithSpan(ctx, span)
+}
+
+// openTracingHeaderCarrier adapts the map[string][]byte header
+// representation ProducerMessage/consumed messages use to
+// opentracing.TextMapWriter/TextMapReader.
+type openTracingHeaderCarrier map[string][]byte
+
+func (c openTracingHeaderCarrier) Set(key, val string) { c[key] = []byte(val) }
+
+func (c openTracingHeaderCarrier) ForeachKey(handler func(key, val string) error) error {
+	for k, v := range c {
+		if err := handler(k, string(v)); err != nil {
+			return err
+		}
+	}
+	return nil
+}